@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+type rateShortfall struct {
+	// Requested and achieved rates in requests per second
+	Requested float64 `json:"requested" yaml:"requested"`
+	Achieved  float64 `json:"achieved" yaml:"achieved"`
+}
+
+// The mean rate an endpoint's pacer is expected to send requests at
+func expectedRate(query endpointQuery) float64 {
+	rate := float64(query.RequestRate)
+	if query.Pacer.Type == "burst" {
+		on, _ := time.ParseDuration(query.Pacer.On)
+		off, _ := time.ParseDuration(query.Pacer.Off)
+		if on+off > 0 {
+			rate *= float64(on) / float64(on+off)
+		}
+	}
+	return rate
+}
+
+// Report the rates of an endpoint if it achieved less than the given ratio
+// of its expected rate, or return nil otherwise
+func checkAchievedRate(endpoint endpointDetails, ratio float64) *rateShortfall {
+	requested := expectedRate(endpoint.Query)
+	if requested <= 0 || endpoint.Metrics.Rate >= requested*ratio {
+		return nil
+	}
+	return &rateShortfall{Requested: requested, Achieved: endpoint.Metrics.Rate}
+}
+
+func rateShortfallWarning(endpoint endpointDetails) string {
+	shortfall := endpoint.RateShortfall
+	return "WARNING: " + endpointName(endpoint) + " was only queried at " + formatRate(shortfall.Achieved) +
+		", " + formatPercent(shortfall.Achieved/shortfall.Requested) + " of the requested " + formatRate(shortfall.Requested) +
+		", so the load generator or network was the bottleneck and its latency isn't meaningful"
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(math.Round(rate*100)/100, 'f', -1, 64) + " req/s"
+}
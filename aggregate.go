@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Bisections locating a latency, or a quantile, in a latency distribution
+const aggregateBisections = 40
+
+// The share of the run's traffic an endpoint represents: its request count
+// scaled by its weight, which defaults to 1
+func trafficShare(endpoint endpointDetails) float64 {
+	weight := endpoint.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	return weight * float64(endpoint.Metrics.Requests)
+}
+
+// The latency at 99% of the whole run, from the endpoints' latency
+// distributions merged in proportion to their traffic share, rather than an
+// average of their latency at 99% which a slow but quiet endpoint skews
+func aggregateP99(endpoints []endpointDetails) time.Duration {
+	var total float64
+	var high time.Duration
+	for i := range endpoints {
+		total += trafficShare(endpoints[i])
+		if endpoints[i].Metrics.Latencies.Max > high {
+			high = endpoints[i].Metrics.Latencies.Max
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	// Find the lowest latency which at least 99% of the traffic is under
+	var low time.Duration
+	for i := 0; i < aggregateBisections && high-low > 1; i++ {
+		middle := low + (high-low)/2
+		var under float64
+		for j := range endpoints {
+			under += trafficShare(endpoints[j]) * shareUnder(endpoints[j].Metrics.Latencies, middle)
+		}
+		if under/total >= 0.99 {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+	return high
+}
+
+// The share of requests with at most the latency, inverting the quantiles of
+// the distribution the metrics estimate
+func shareUnder(latencies vegeta.LatencyMetrics, latency time.Duration) float64 {
+	if latency >= latencies.Max {
+		return 1
+	}
+	low, high := 0.0, 1.0
+	for i := 0; i < aggregateBisections; i++ {
+		middle := (low + high) / 2
+		if latencies.Quantile(middle) <= latency {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return low
+}
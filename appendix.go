@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// Lines of configuration printed in the appendix before it's truncated,
+// about ten pages
+const maxConfigAppendixLines = 800
+
+// Add an appendix with the effective configuration of the endpoints, so the
+// report is a complete record of how its results were produced
+func writeConfigAppendix(pdf *gofpdf.Fpdf, endpoints []endpointDetails, environment runEnvironment) {
+	config, err := json.MarshalIndent(effectiveConfig(endpoints), "", "  ")
+	if err != nil {
+		fatal(exitReasonError, err)
+	}
+	lines := strings.Split(string(config), "\n")
+
+	pdf.AddPage()
+	pdf.SetFont("ArialTrue", "B", 11)
+	_, lineHt := pdf.GetFontSize()
+	lineHt *= 1.2
+	pdf.CellFormat(0, lineHt, "Appendix: Configuration", "", 1, "L", false, 0, "")
+	pdf.SetFont("ArialTrue", "", 10)
+	_, lineHt = pdf.GetFontSize()
+	lineHt *= 1.2
+	pdf.MultiCell(0, lineHt, "The effective configuration of the endpoints, after defaults and rate adjustments "+
+		"were applied (config "+environment.ConfigHash[:12]+").", "", "L", false)
+	pdf.Ln(lineHt)
+
+	// Courier is a core font, so its text must be translated from UTF-8
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFont("Courier", "", 8)
+	_, lineHt = pdf.GetFontSize()
+	lineHt *= 1.2
+	for i, line := range lines {
+		if i == maxConfigAppendixLines {
+			pdf.Ln(lineHt)
+			pdf.SetFont("ArialTrue", "I", 10)
+			pdf.MultiCell(0, lineHt, "Truncated after "+strconv.Itoa(maxConfigAppendixLines)+" of "+
+				strconv.Itoa(len(lines))+" lines, use --json for the full configuration.", "", "L", false)
+			break
+		}
+		pdf.MultiCell(0, lineHt, translate(line), "", "L", false)
+	}
+	pdf.SetFont("ArialTrue", "", 10)
+}
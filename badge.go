@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/vgsvg"
+)
+
+// Dimensions of the badge, in points
+const (
+	badgeHeight   = 20
+	badgePadding  = 6
+	badgeFontSize = 11
+)
+
+var (
+	badgeLabelColor   = color.RGBA{R: 0x55, G: 0x55, B: 0x55, A: 0xff}
+	badgePassingColor = color.RGBA{R: 0x44, G: 0xcc, B: 0x11, A: 0xff}
+	badgeFailingColor = color.RGBA{R: 0xe0, G: 0x5d, B: 0x44, A: 0xff}
+)
+
+// Render an SVG badge stating whether every endpoint passed, with the worst
+// latency at 99%, e.g. "API latency | passing, P99 12.3ms"
+func createBadge(endpoints []endpointDetails, threshold float64) *bytes.Buffer {
+	passing := len(endpoints) > 0
+	var worst float64
+	for i := range endpoints {
+		if !endpointPassed(endpoints[i], threshold) {
+			passing = false
+		}
+		if p99 := durationToMs(endpoints[i].Metrics.Latencies.P99); p99 > worst {
+			worst = p99
+		}
+	}
+	status, statusColor := "failing", badgeFailingColor
+	if passing {
+		status, statusColor = "passing", badgePassingColor
+	}
+	status += ", P99 " + formatMs(worst)
+
+	font, err := vg.MakeFont("Helvetica", badgeFontSize)
+	if err != nil {
+		fatal(exitReasonError, err)
+	}
+	label := "API latency"
+	labelWidth := font.Width(label) + 2*badgePadding
+	statusWidth := font.Width(status) + 2*badgePadding
+	canvas := vgsvg.New(labelWidth+statusWidth, badgeHeight)
+
+	fillRect(canvas, 0, labelWidth, badgeLabelColor)
+	fillRect(canvas, labelWidth, labelWidth+statusWidth, statusColor)
+	// Center the text vertically between its ascent and descent, which is
+	// negative as it's below the baseline
+	extents := font.Extents()
+	baseline := (badgeHeight - extents.Ascent - extents.Descent) / 2
+	canvas.SetColor(color.White)
+	canvas.FillString(font, vg.Point{X: badgePadding, Y: baseline}, label)
+	canvas.FillString(font, vg.Point{X: labelWidth + badgePadding, Y: baseline}, status)
+
+	buffer := new(bytes.Buffer)
+	if _, err := canvas.WriteTo(buffer); err != nil {
+		fatal(exitReasonError, err)
+	}
+	return buffer
+}
+
+func fillRect(canvas vg.Canvas, from, to vg.Length, fill color.Color) {
+	var path vg.Path
+	path.Move(vg.Point{X: from, Y: 0})
+	path.Line(vg.Point{X: to, Y: 0})
+	path.Line(vg.Point{X: to, Y: badgeHeight})
+	path.Line(vg.Point{X: from, Y: badgeHeight})
+	path.Close()
+	canvas.SetColor(fill)
+	canvas.Fill(path)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Largest body which can be generated, as it's held in memory for the whole run
+const maxBodySize = 1 << 30
+
+// Multipliers of the supported size units, from the longest suffix to the shortest
+var byteSizeUnits = []struct {
+	Suffix     string
+	Multiplier int64
+}{
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// Parse a size such as "512", "64KB" or "1.5MB", where units are powers of 1024
+func parseByteSize(value string) int64 {
+	size, err := byteSize(value)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	return size
+}
+
+func byteSize(value string) (int64, error) {
+	size := strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(size, unit.Suffix) {
+			size = strings.TrimSpace(strings.TrimSuffix(size, unit.Suffix))
+			multiplier = unit.Multiplier
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(size, 64)
+	if err != nil || !(number >= 0) {
+		return 0, errors.New("invalid body size: " + value)
+	}
+	// Compared before the conversion, which would overflow on huge sizes
+	bytes := number * float64(multiplier)
+	if bytes > maxBodySize {
+		return 0, errors.New("body size " + value + " exceeds the maximum of " + formatBytes(maxBodySize))
+	}
+	return int64(bytes), nil
+}
+
+// Reject a synthetic body generateBody can't generate
+func checkBodySize(target endpointTarget) error {
+	if target.BodySize == "" {
+		return nil
+	}
+	if _, err := byteSize(target.BodySize); err != nil {
+		return err
+	}
+	if target.BodyFill != "" && target.BodyFill != "zero" && target.BodyFill != "random" {
+		return errors.New("unknown body fill: " + target.BodyFill)
+	}
+	return nil
+}
+
+// Generate a synthetic body of the given size, either zero filled or random
+func generateBody(size string, fill string) []byte {
+	body := make([]byte, parseByteSize(size))
+	switch fill {
+	case "", "zero":
+	case "random":
+		if _, err := rand.Read(body); err != nil {
+			fatal(exitReasonInvalidInput, err)
+		}
+	default:
+		fatal(exitReasonInvalidInput, "Unknown body fill: "+fill)
+	}
+	return body
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Environment variable holding the passphrase of encrypted client keys, unless
+// the endpoint names another one
+const defaultClientKeyPassphraseEnv = "RTAPI_CLIENT_KEY_PASSPHRASE"
+
+// Build the TLS configuration presenting the endpoint's client certificate,
+// on top of vegeta's default configuration
+func clientTLSConfig(target endpointTarget) (*tls.Config, error) {
+	certPEM, err := ioutil.ReadFile(target.ClientCert)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(target.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err = decryptClientKey(keyPEM, target.ClientKeyPassphraseEnv)
+	if err != nil {
+		return nil, errors.New(target.ClientKey + ": " + err.Error())
+	}
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	config := vegeta.DefaultTLSConfig.Clone()
+	config.Certificates = []tls.Certificate{certificate}
+	return config, nil
+}
+
+// Decrypt a PEM key encrypted with a passphrase (RFC 1423), read from the
+// given environment variable so it never appears in the config or results.
+// Unencrypted keys are returned as they are
+func decryptClientKey(keyPEM []byte, passphraseEnv string) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || !x509.IsEncryptedPEMBlock(block) {
+		return keyPEM, nil
+	}
+	if passphraseEnv == "" {
+		passphraseEnv = defaultClientKeyPassphraseEnv
+	}
+	passphrase, ok := os.LookupEnv(passphraseEnv)
+	if !ok {
+		return nil, errors.New("the key is encrypted but " + passphraseEnv + " isn't set")
+	}
+	der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Percentiles given a confidence interval, and the z-score of its 95% confidence
+var confidencePercentiles = []float64{95, 99}
+
+const confidenceZ = 1.96
+
+// Fewest requests slower than a percentile for it to be trusted
+const minTailSamples = 10
+
+type percentileInterval struct {
+	Percentile float64       `json:"percentile" yaml:"percentile"`
+	Latency    time.Duration `json:"latency" yaml:"latency"`
+	// Approximate 95% confidence interval of the latency
+	Lower time.Duration `json:"lower" yaml:"lower"`
+	Upper time.Duration `json:"upper" yaml:"upper"`
+	// Number of requests slower than the percentile, which back it
+	TailSamples uint64 `json:"tail_samples" yaml:"tail_samples"`
+	// Whether too few requests back the percentile to trust it
+	Uncertain bool `json:"uncertain" yaml:"uncertain"`
+}
+
+// Estimate a distribution-free confidence interval of the latency at P95 and
+// P99: the rank of the requests around the percentile follows a binomial
+// distribution, approximated by a normal one, and the latencies at the lower
+// and upper ranks bound the interval. The latencies are only known right
+// after the run, so this can't be computed from saved metrics
+func percentileIntervals(metrics vegeta.Metrics) []percentileInterval {
+	n := float64(metrics.Requests)
+	if n == 0 {
+		return nil
+	}
+	intervals := make([]percentileInterval, len(confidencePercentiles))
+	for i, percentile := range confidencePercentiles {
+		p := percentile / 100
+		spread := confidenceZ * math.Sqrt(n*p*(1-p))
+		lower := math.Max(math.Floor(n*p-spread), 1)
+		upper := math.Min(math.Ceil(n*p+spread), n)
+		tail := uint64(math.Floor(n * (1 - p)))
+		intervals[i] = percentileInterval{
+			Percentile:  percentile,
+			Latency:     latencyAtPercentile(metrics, percentile),
+			Lower:       metrics.Latencies.Quantile(lower / n),
+			Upper:       metrics.Latencies.Quantile(upper / n),
+			TailSamples: tail,
+			Uncertain:   tail < minTailSamples,
+		}
+		if upper >= n {
+			// The slowest request is the last rank
+			intervals[i].Upper = metrics.Latencies.Max
+		}
+	}
+	return intervals
+}
+
+func (interval percentileInterval) String() string {
+	summary := "P" + formatPercentile(interval.Percentile) + " " + formatMs(durationToMs(interval.Latency)) +
+		" (95% CI " + formatMs(durationToMs(interval.Lower)) + " to " + formatMs(durationToMs(interval.Upper)) +
+		", " + strconv.FormatUint(interval.TailSamples, 10) + " slower requests"
+	if interval.Uncertain {
+		summary += ", too few to trust"
+	}
+	return summary + ")"
+}
+
+func percentileIntervalsSummary(intervals []percentileInterval) string {
+	summaries := make([]string, len(intervals))
+	for i, interval := range intervals {
+		summaries[i] = interval.String()
+	}
+	return strings.Join(summaries, "; ")
+}
+
+// Whether any of the percentiles is backed by too few requests
+func uncertainPercentiles(intervals []percentileInterval) bool {
+	for _, interval := range intervals {
+		if interval.Uncertain {
+			return true
+		}
+	}
+	return false
+}
+
+func printPercentileIntervals(intervals []percentileInterval) {
+	os.Stdout.Write([]byte("Percentile confidence: " + percentileIntervalsSummary(intervals) + "\n"))
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Whether the config file is served over HTTP(S) rather than a local path
+func isConfigURL(file string) bool {
+	return strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://")
+}
+
+// Fetch a config served over HTTP(S), retrying transient failures with a
+// backoff, and return the path of a local copy for the file parsers to read.
+// Every fetched config which parses is cached, so a scheduled run survives an
+// outage of the config service, or an error page served in its place, by
+// falling back on the last copy fetched within the cache TTL, saying so. A
+// client side (4xx) error means the URL itself is wrong, so it's fatal. The
+// extension of the URL's path tells the format
+func fetchConfig(rawURL string, attempts int, cacheTTL time.Duration) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	ext := path.Ext(parsed.Path)
+	if ext != ".json" && ext != ".yml" && ext != ".yaml" {
+		fatal(exitReasonInvalidInput, "The config URL "+rawURL+" must end in .json, .yml or .yaml")
+	}
+	cached, err := configCachePath(rawURL, ext)
+	if err != nil {
+		fatal(exitReasonError, err)
+	}
+
+	if attempts < 1 {
+		attempts = 1
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	config, err := withRetry(context.Background(), attempts, "Fetching the config from "+rawURL, func() ([]byte, error) {
+		return get(client, rawURL)
+	})
+	var status statusError
+	if errors.As(err, &status) && status.Code < 500 {
+		fatal(exitReasonInvalidInput, "Fetching the config from "+rawURL+" failed: "+err.Error())
+	}
+	if err == nil {
+		err = checkConfig(config, ext)
+	}
+	if err == nil {
+		err := writeFileAtomically(cached, config)
+		if err == nil {
+			return cached
+		}
+		// Still run with the fetched config, from a temporary copy
+		log.Print("Caching the config from ", rawURL, " failed: ", err)
+		temp, err := ioutil.TempFile("", "rtapi-config-*"+ext)
+		if err == nil {
+			_, err = temp.Write(config)
+			temp.Close()
+		}
+		if err != nil {
+			fatal(exitReasonError, err)
+		}
+		return temp.Name()
+	}
+
+	info, statErr := os.Stat(cached)
+	if statErr != nil || cacheTTL <= 0 || time.Since(info.ModTime()) > cacheTTL {
+		fatal(exitReasonError, "Fetching the config from "+rawURL+" failed and no copy was cached within "+
+			cacheTTL.String()+": "+err.Error())
+	}
+	log.Print("Fetching the config from ", rawURL, " failed, using the copy cached at ",
+		info.ModTime().Format(time.RFC3339), ": ", err)
+	return cached
+}
+
+// Check that a fetched config parses into endpoints before it replaces the
+// cached copy
+func checkConfig(config []byte, ext string) error {
+	var endpoints []endpointDetails
+	var err error
+	if ext == ".json" {
+		err = json.Unmarshal(config, &endpoints)
+	} else {
+		err = yaml.Unmarshal(config, &endpoints)
+	}
+	if err != nil {
+		return errors.New("invalid config: " + err.Error())
+	}
+	if len(endpoints) == 0 {
+		return errors.New("invalid config: no endpoints")
+	}
+	return nil
+}
+
+// Where the last config fetched from a URL is cached, in the user's cache
+// directory
+func configCachePath(rawURL string, ext string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "rtapi", "configs")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+ext), nil
+}
+
+// Replace the file in one step, so a failed write never leaves a truncated
+// config in the cache
+func writeFileAtomically(file string, data []byte) error {
+	temp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+func get(client *http.Client, rawURL string) ([]byte, error) {
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, statusError{Code: resp.StatusCode, Body: string(body)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, errors.New("empty config")
+	}
+	return body, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Media type of a Content-Type header, without its parameters such as the charset
+func mediaType(contentType string) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return media
+}
+
+// Reclassify a 2xx response without the expected media type as a failed
+// request, like an HTML error page served with a 200. It keeps its status
+// code, so it doesn't fail the expected status too, and the mismatch is
+// recorded in its error. Redirects carry no content of their own, so they
+// aren't checked. Returns whether the response was reclassified
+func checkContentType(response *vegeta.Result, expected string) bool {
+	if response.Error != "" || response.Code < 200 || response.Code >= 300 {
+		return false
+	}
+	actual := response.Headers.Get("Content-Type")
+	if mediaType(actual) == mediaType(expected) {
+		return false
+	}
+	if actual == "" {
+		actual = "none"
+	}
+	response.Error = "unexpected content type: " + actual + " (status " + strconv.Itoa(int(response.Code)) + ")"
+	return true
+}
+
+// Take the reclassified responses out of the success ratio, which vegeta
+// computes from the status codes alone
+func discountContentTypeMismatches(metrics *vegeta.Metrics, mismatches uint64) {
+	if metrics.Requests == 0 || mismatches == 0 {
+		return
+	}
+	metrics.Success = math.Max(metrics.Success-float64(mismatches)/float64(metrics.Requests), 0)
+}
+
+func contentTypeMismatchWarning(endpoint endpointDetails) string {
+	share := float64(endpoint.ContentTypeMismatches) / float64(endpoint.Metrics.Requests)
+	return "FAILED: " + endpointName(endpoint) + " must return " + mediaType(endpoint.ExpectContentType) + " but " +
+		strconv.FormatUint(endpoint.ContentTypeMismatches, 10) + " 2xx responses (" + formatPercent(share) + ") didn't"
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type elasticSettings struct {
+	Url   string `json:"url" yaml:"url"`
+	Index string `json:"index" yaml:"index"`
+	// Either an API key or a username and password, if the cluster needs auth
+	APIKey   string `json:"api_key" yaml:"api_key"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+type elasticDocument struct {
+	Timestamp time.Time       `json:"@timestamp"`
+	Endpoint  endpointDetails `json:"endpoint"`
+}
+
+type elasticBulkAction struct {
+	Index struct {
+		Index string `json:"_index"`
+	} `json:"index"`
+}
+
+type elasticBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Index one document per endpoint, stamped with the end of its attack, in a
+// single request to the _bulk API
+func sendMetricsToElastic(ctx context.Context, endpoints []endpointDetails, elasticSettings elasticSettings) error {
+	var action elasticBulkAction
+	action.Index.Index = elasticSettings.Index
+	actionJson, err := json.Marshal(action)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	for i := range endpoints {
+		document, err := json.Marshal(elasticDocument{Timestamp: endpoints[i].Metrics.End, Endpoint: redactEndpoint(endpoints[i])})
+		if err != nil {
+			return err
+		}
+		body.Write(actionJson)
+		body.WriteByte('\n')
+		body.Write(document)
+		body.WriteByte('\n')
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-ndjson")
+	if elasticSettings.APIKey != "" {
+		header.Set("Authorization", "ApiKey "+elasticSettings.APIKey)
+	} else if elasticSettings.Username != "" {
+		credentials := elasticSettings.Username + ":" + elasticSettings.Password
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	}
+	respBody, err := postWithRetry(ctx, strings.TrimSuffix(elasticSettings.Url, "/")+"/_bulk", header, body.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// The _bulk API succeeds as a whole even if some documents were rejected
+	var response elasticBulkResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return err
+	}
+	if !response.Errors {
+		return nil
+	}
+	// Items are in the order of the documents, one per endpoint
+	rejected := sendErrors{Total: len(endpoints)}
+	for i, item := range response.Items {
+		for _, result := range item {
+			if result.Error.Reason != "" && i < len(endpoints) {
+				rejected.Errors = append(rejected.Errors, errors.New(endpointName(endpoints[i])+": "+result.Error.Type+": "+result.Error.Reason))
+				rejected.Failed = append(rejected.Failed, i)
+			}
+		}
+	}
+	if len(rejected.Errors) == 0 {
+		return errors.New("some documents were rejected")
+	}
+	return rejected
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+const version = "v0.2.0"
+
+// The machine and configuration a run was made with, recorded to make results reproducible
+type runEnvironment struct {
+	Hostname   string `json:"hostname" yaml:"hostname"`
+	OS         string `json:"os" yaml:"os"`
+	Arch       string `json:"arch" yaml:"arch"`
+	CPUs       int    `json:"cpus" yaml:"cpus"`
+	Version    string `json:"version" yaml:"version"`
+	ConfigHash string `json:"config_hash" yaml:"config_hash"`
+}
+
+func collectEnvironment(endpoints []endpointDetails) (runEnvironment, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return runEnvironment{}, err
+	}
+	hash, err := configHash(endpoints)
+	if err != nil {
+		return runEnvironment{}, err
+	}
+	return runEnvironment{
+		Hostname:   hostname,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+		Version:    version,
+		ConfigHash: hash,
+	}, nil
+}
+
+// Configuration of an endpoint, without its results
+type endpointConfig struct {
+	Name   string         `json:"name"`
+	Weight float64        `json:"weight"`
+	Target endpointTarget `json:"target"`
+	Query  endpointQuery  `json:"query_parameters"`
+}
+
+// The effective configuration of the endpoints, i.e. after defaults and rate
+// adjustments have been applied
+func effectiveConfig(endpoints []endpointDetails) []endpointConfig {
+	configs := make([]endpointConfig, len(endpoints))
+	for i := range endpoints {
+		configs[i] = endpointConfig{endpoints[i].Name, endpoints[i].Weight, endpoints[i].Target, endpoints[i].Query}
+	}
+	return configs
+}
+
+// Hash the effective configuration of the endpoints, ignoring any results
+func configHash(endpoints []endpointDetails) (string, error) {
+	jsonInfo, err := json.Marshal(effectiveConfig(endpoints))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(jsonInfo)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// One line summary of the environment for the PDF footer
+func (environment runEnvironment) String() string {
+	return "Generated on " + environment.Hostname + " (" + environment.OS + "/" + environment.Arch + ", " +
+		strconv.Itoa(environment.CPUs) + " CPUs) by rtapi " + environment.Version +
+		", config " + environment.ConfigHash[:12]
+}
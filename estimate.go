@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+// Counts the bytes written to it without keeping them
+type countingWriter struct {
+	bytes int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.bytes += int64(len(p))
+	return len(p), nil
+}
+
+// Lay out the PDF report and report its size instead of writing it, so
+// big runs can pick a lighter output before paying for the PDF
+func estimatePDF(endpoints []endpointDetails, graphOptions graphOptions, environment runEnvironment) {
+	pdf := buildPDF(endpoints, graphOptions, environment)
+	var counter countingWriter
+	if err := pdf.Output(&counter); err != nil {
+		fatal(exitReasonError, err)
+	}
+	graphs := 0
+	if !graphOptions.NoGraph {
+		graphs++
+		if graphOptions.ErrorBand > 0 {
+			graphs++
+		}
+		if graphOptions.RateTimeline {
+			graphs++
+		}
+	}
+	os.Stderr.Write([]byte("The PDF report would have " + strconv.Itoa(pdf.PageCount()) + " pages and " +
+		strconv.Itoa(graphs) + " graphs, and take " + formatBytes(counter.bytes) + "\n"))
+}
+
+// Format a size in bytes in powers of 1024, e.g. "1.5 MB", like parseByteSize reads them
+func formatBytes(size int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + " " + units[unit]
+}
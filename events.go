@@ -0,0 +1,61 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// An action taken during the test, marked on the time axis of graphs
+type graphEvent struct {
+	// Time since the start of the endpoint's attack
+	Offset time.Duration
+	Label  string
+}
+
+// Parse a comma separated list of events such as "30s:deploy,60s:cache-flush"
+func parseEvents(value string) []graphEvent {
+	var events []graphEvent
+	for _, field := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(field), ":", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			fatal(exitReasonInvalidInput, "Invalid event, expected offset:label: "+field)
+		}
+		offset, err := time.ParseDuration(parts[0])
+		if err != nil || offset < 0 {
+			fatal(exitReasonInvalidInput, "Invalid event offset: "+parts[0])
+		}
+		events = append(events, graphEvent{Offset: offset, Label: parts[1]})
+	}
+	return events
+}
+
+// Draw a labeled vertical line from the bottom of the graph up to top at
+// the time of each event
+func addEventMarkers(p *plot.Plot, events []graphEvent, top float64) {
+	for _, event := range events {
+		x := event.Offset.Seconds()
+		line, err := plotter.NewLine(plotter.XYs{{X: x, Y: 0}, {X: x, Y: top}})
+		if err != nil {
+			panic(err)
+		}
+		line.Color = color.Gray{Y: 96}
+		line.Width = vg.Points(1)
+		line.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+		p.Add(line)
+
+		label, err := plotter.NewLabels(plotter.XYLabels{
+			XYs:    plotter.XYs{{X: x, Y: top}},
+			Labels: []string{" " + event.Label},
+		})
+		if err != nil {
+			panic(err)
+		}
+		label.TextStyle[0].Color = color.Gray{Y: 96}
+		p.Add(label)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Reasons reported on the last line of stderr whenever rtapi exits non-zero.
+// These are part of the CLI's interface, so existing values must not change
+const (
+	// The flags or input files are invalid
+	exitReasonInvalidInput = "invalid_input"
+	// An endpoint's latency breached its threshold
+	exitReasonSLOBreach = "slo_breach"
+	// An endpoint's latency grew by more than --max-regression over the baseline
+	exitReasonRegression = "regression"
+	// A request to an endpoint took longer than its maximum latency
+	exitReasonMaxLatency = "max_latency"
+	// An endpoint returned another status code than the one it expects
+	exitReasonStatusMismatch = "status_mismatch"
+	// An endpoint returned successful responses of another content type than the one it expects
+	exitReasonContentTypeMismatch = "content_type_mismatch"
+	// An endpoint failed more requests than its maximum number of errors
+	exitReasonTooManyErrors = "too_many_errors"
+	// An endpoint wasn't queried at the rate it was meant to be
+	exitReasonRateShortfall = "rate_shortfall"
+	// No request to an endpoint got a response, every one of them timing out or failing to connect
+	exitReasonUnreachable = "unreachable"
+	// The benchmark ran but exporting its results failed
+	exitReasonExportFailure = "export_failure"
+	// rtapi was interrupted while exporting the results
+	exitReasonInterrupted = "interrupted"
+	// The latency measured by --self-test didn't match the injected latency
+	exitReasonSelfTestFailure = "self_test_failure"
+	// Anything else, such as failing to write an output
+	exitReasonError = "error"
+)
+
+// Exit codes of each class of failure
+const (
+	exitInternalError     = 1
+	exitConfigError       = 2
+	exitSLOBreach         = 3
+	exitReliabilityBreach = 4
+	exitExportFailure     = 5
+)
+
+// Exit code of each exit reason
+var exitCodes = map[string]int{
+	exitReasonInvalidInput:        exitConfigError,
+	exitReasonSLOBreach:           exitSLOBreach,
+	exitReasonRegression:          exitSLOBreach,
+	exitReasonMaxLatency:          exitSLOBreach,
+	exitReasonStatusMismatch:      exitReliabilityBreach,
+	exitReasonContentTypeMismatch: exitReliabilityBreach,
+	exitReasonTooManyErrors:       exitReliabilityBreach,
+	exitReasonRateShortfall:       exitReliabilityBreach,
+	exitReasonUnreachable:         exitReliabilityBreach, // Overridden by --timeout-exit-code
+	exitReasonExportFailure:       exitExportFailure,
+	exitReasonInterrupted:         exitExportFailure,
+	exitReasonSelfTestFailure:     exitInternalError,
+	exitReasonError:               exitInternalError,
+}
+
+type exitReport struct {
+	Exit string `json:"exit"`
+	// Endpoints (or exporters) responsible for the exit, if any
+	Failed []string `json:"failed,omitempty"`
+}
+
+// exitError is returned by the app action to exit with a reason
+type exitError struct {
+	Reason  string
+	Failed  []string
+	message string
+}
+
+var _ cli.ExitCoder = exitError{}
+
+func (e exitError) Error() string {
+	return e.message
+}
+
+func (e exitError) ExitCode() int {
+	return exitCodes[e.Reason]
+}
+
+func exitWith(reason string, failed []string, message string) exitError {
+	return exitError{Reason: reason, Failed: failed, message: message}
+}
+
+// Print the error returned by the app action, followed by its exit reason
+func handleExitError(c *cli.Context, err error) {
+	if err == nil {
+		return
+	}
+	report := exitReport{Exit: exitReasonError}
+	if exit, ok := err.(exitError); ok {
+		report = exitReport{Exit: exit.Reason, Failed: exit.Failed}
+	}
+	if err.Error() != "" {
+		os.Stderr.Write([]byte(err.Error() + "\n"))
+	}
+	printExitReport(report)
+	os.Exit(exitCodes[report.Exit])
+}
+
+func recoverInternalError() {
+	if r := recover(); r != nil {
+		os.Stderr.Write(debug.Stack())
+		fatal(exitReasonError, "panic: ", r)
+	}
+}
+
+// Log the message and exit with the given reason, like log.Fatal
+func fatal(reason string, v ...interface{}) {
+	log.Print(v...)
+	printExitReport(exitReport{Exit: reason})
+	os.Exit(exitCodes[reason])
+}
+
+func printExitReport(report exitReport) {
+	jsonInfo, _ := json.Marshal(report)
+	os.Stderr.Write(append(jsonInfo, '\n'))
+}
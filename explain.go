@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describe in plain English why an endpoint passed or failed
+func explainEndpoint(endpoint endpointDetails, threshold float64) string {
+	metrics := endpoint.Metrics
+	if metrics.Requests == 0 {
+		return endpointName(endpoint) + ": no requests were completed, so its latency could not be measured."
+	}
+
+	var sentences []string
+	p99 := durationToMs(metrics.Latencies.P99)
+	if p99 > threshold {
+		sentences = append(sentences, "P99 "+formatMs(p99)+" exceeds the "+formatMs(threshold)+
+			" threshold by "+formatMs(p99-threshold))
+	} else {
+		sentences = append(sentences, "P99 "+formatMs(p99)+" is within the "+formatMs(threshold)+
+			" threshold with "+formatMs(threshold-p99)+" to spare")
+	}
+
+	codes := make([]string, 0, len(metrics.StatusCodes))
+	for code := range metrics.StatusCodes {
+		if status, err := strconv.Atoi(code); err != nil || status < 200 || status >= 400 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		share := formatPercent(float64(metrics.StatusCodes[code]) / float64(metrics.Requests))
+		if code == "0" {
+			sentences = append(sentences, share+" of requests failed without a response")
+		} else {
+			sentences = append(sentences, share+" of requests returned "+code)
+		}
+	}
+	if len(codes) == 0 && endpoint.ContentTypeMismatches == 0 {
+		sentences = append(sentences, "all "+strconv.FormatUint(metrics.Requests, 10)+" requests succeeded")
+	} else if len(codes) == 0 {
+		sentences = append(sentences, "all "+strconv.FormatUint(metrics.Requests, 10)+" requests returned a successful status code")
+	}
+
+	if endpoint.StatusMismatches > 0 {
+		sentences = append(sentences, formatPercent(float64(endpoint.StatusMismatches)/float64(metrics.Requests))+
+			" of requests didn't return the expected "+strconv.Itoa(endpoint.ExpectStatus))
+	}
+
+	if endpoint.ContentTypeMismatches > 0 {
+		sentences = append(sentences, formatPercent(float64(endpoint.ContentTypeMismatches)/float64(metrics.Requests))+
+			" of requests were answered with another content type than "+mediaType(endpoint.ExpectContentType))
+	}
+
+	if endpoint.MaxErrors != nil {
+		sentences = append(sentences, strconv.FormatUint(endpoint.Errors, 10)+" requests failed against a maximum of "+
+			strconv.Itoa(*endpoint.MaxErrors))
+	}
+
+	if limit, err := maxLatency(endpoint); err == nil && limit > 0 {
+		sentences = append(sentences, "its slowest request took "+formatMs(durationToMs(endpoint.Metrics.Latencies.Max))+
+			" against a maximum of "+formatMs(durationToMs(limit)))
+	}
+
+	if endpoint.RateShortfall != nil {
+		sentences = append(sentences, "it was only queried at "+formatPercent(endpoint.RateShortfall.Achieved/endpoint.RateShortfall.Requested)+
+			" of the requested rate, so its latency doesn't reflect the requested load")
+	}
+
+	if endpoint.RateLimited != nil {
+		sentences = append(sentences, "the endpoint rate limited the test, so its latency doesn't reflect the requested load")
+	}
+
+	verdict := "passed"
+	if !endpointPassed(endpoint, threshold) {
+		verdict = "failed"
+	}
+	return endpointName(endpoint) + " " + verdict + ": " + strings.Join(sentences, "; ") + "."
+}
+
+// Whether an endpoint's latency at 99% is within the threshold and it met
+// all its other expectations
+func endpointPassed(endpoint endpointDetails, threshold float64) bool {
+	return durationToMs(endpoint.Metrics.Latencies.P99) <= threshold && endpoint.StatusMismatches == 0 &&
+		endpoint.ContentTypeMismatches == 0 && !tooManyErrors(endpoint) && !exceedsMaxLatency(endpoint)
+}
+
+func printExplanations(endpoints []endpointDetails, threshold float64) {
+	for i := range endpoints {
+		os.Stdout.Write([]byte(explainEndpoint(endpoints[i], threshold) + "\n"))
+	}
+}
+
+func durationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Format milliseconds with at most two decimals, e.g. "42ms" or "12.35ms"
+func formatMs(ms float64) string {
+	return strconv.FormatFloat(math.Round(ms*100)/100, 'f', -1, 64) + "ms"
+}
+
+// Format a ratio as a percentage with at most two decimals, e.g. "0.3%"
+func formatPercent(ratio float64) string {
+	return strconv.FormatFloat(math.Round(ratio*10000)/100, 'f', -1, 64) + "%"
+}
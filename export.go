@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Number of times an export is attempted before giving up, and the delay
+// before the first retry, doubled on every subsequent one
+const (
+	exportAttempts = 3
+	exportBackoff  = time.Second
+)
+
+// Load a JSON or YAML settings file into the given value
+func parseSettingsFile(file string, settings interface{}) {
+	byteValue, err := ioutil.ReadFile(file)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	switch filepath.Ext(file) {
+	case ".json":
+		err = json.Unmarshal(byteValue, settings)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(byteValue, settings)
+	default:
+		err = errors.New("settings file " + file + " must be a JSON or YAML file")
+	}
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+}
+
+// POST a JSON body, retrying with an exponential backoff on network errors
+// and server side (5xx) failures until the context is done, and return the
+// body of the successful response
+func postWithRetry(ctx context.Context, url string, header http.Header, body []byte) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	return withRetry(ctx, exportAttempts, "Export to "+url, func() ([]byte, error) {
+		return post(ctx, client, url, header, body)
+	})
+}
+
+// Call request up to the given number of attempts with an exponential
+// backoff, until it succeeds, fails on a client side (4xx) error or the
+// context is done, logging each failed attempt of the described action
+func withRetry(ctx context.Context, attempts int, action string, request func() ([]byte, error)) ([]byte, error) {
+	backoff := exportBackoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		var respBody []byte
+		respBody, err = request()
+		// A request which succeeded was delivered, even if the context is
+		// done by now
+		if err == nil {
+			return respBody, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var status statusError
+		if errors.As(err, &status) && status.Code < 500 {
+			// The request itself was rejected, retrying won't help
+			return nil, err
+		}
+		os.Stderr.Write([]byte(action + " failed (attempt " + strconv.Itoa(attempt) + "): " + err.Error() + "\n"))
+	}
+	return nil, err
+}
+
+func post(ctx context.Context, client *http.Client, url string, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, statusError{Code: resp.StatusCode, Body: string(respBody)}
+	}
+	return respBody, nil
+}
+
+// Call send for each of n events, running at most concurrency of them at
+// once to stay within the rate limits of the receiving end, and collect the
+// errors of all the events which failed
+func sendConcurrently(n int, concurrency int, send func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	errs := make([]error, n)
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = send(i)
+			<-slots
+		}(i)
+	}
+	wg.Wait()
+	var failed sendErrors
+	for i, err := range errs {
+		if err != nil {
+			failed.Errors = append(failed.Errors, err)
+			failed.Failed = append(failed.Failed, i)
+		}
+	}
+	if len(failed.Errors) == 0 {
+		return nil
+	}
+	failed.Total = n
+	return failed
+}
+
+// The errors of the events which failed to be sent, with their indexes
+type sendErrors struct {
+	Errors []error
+	Failed []int
+	Total  int
+}
+
+func (e sendErrors) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return strconv.Itoa(len(e.Errors)) + " of " + strconv.Itoa(e.Total) + " events failed: " + strings.Join(messages, "; ")
+}
+
+type statusError struct {
+	Code int
+	Body string
+}
+
+func (e statusError) Error() string {
+	return "unexpected status " + strconv.Itoa(e.Code) + ": " + e.Body
+}
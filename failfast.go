@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// How often the running P99 is compared against the threshold when failing fast
+const sloCheckInterval = time.Second
+
+// Minimum number of responses before the running P99 is trusted, so a
+// single slow first request doesn't abort the run
+const sloCheckMinRequests = 20
+
+// Periodically evaluate the P99 of an attack in progress against a threshold
+type sloMonitor struct {
+	// P99 threshold in milliseconds
+	Threshold float64
+	next      time.Time
+}
+
+// Report whether the running P99 exceeds the threshold, evaluating it at most
+// once per check interval
+func (m *sloMonitor) Breached(metrics *vegeta.Metrics) bool {
+	now := time.Now()
+	if now.Before(m.next) || metrics.Requests < sloCheckMinRequests {
+		return false
+	}
+	m.next = now.Add(sloCheckInterval)
+	return durationToMs(metrics.Latencies.Quantile(0.99)) > m.Threshold
+}
@@ -11,7 +11,9 @@ require (
 	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/tsenart/vegeta/v12 v12.8.3
 	github.com/urfave/cli/v2 v2.2.0
+	golang.org/x/crypto v0.0.0-20191122220453-ac88ee75c92c
 	golang.org/x/image v0.0.0-20200119044424-58c23975cae1 // indirect
+	golang.org/x/net v0.0.0-20200226121028-0de0cce0169b
 	gonum.org/v1/netlib v0.0.0-20200317120129-c5a04cffd98a // indirect
 	gonum.org/v1/plot v0.7.1-0.20200415083422-475e39bcda54
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type grafanaSettings struct {
+	Url          string   `json:"url" yaml:"url"`
+	Token        string   `json:"token" yaml:"token"`
+	Tags         []string `json:"tags" yaml:"tags"`
+	DashboardUID string   `json:"dashboard_uid" yaml:"dashboard_uid"`
+	PanelID      int      `json:"panel_id" yaml:"panel_id"`
+}
+
+type grafanaAnnotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	PanelID      int      `json:"panelId,omitempty"`
+	Time         int64    `json:"time"`
+	TimeEnd      int64    `json:"timeEnd"`
+	Tags         []string `json:"tags"`
+	Text         string   `json:"text"`
+}
+
+// Annotate the whole run, from the start of the first attack to the end of
+// the last one, with a summary of each endpoint's results
+func sendAnnotationToGrafana(ctx context.Context, endpoints []endpointDetails, grafanaSettings grafanaSettings) error {
+	var start, end time.Time
+	var summary []string
+	for i := range endpoints {
+		metrics := endpoints[i].Metrics
+		if start.IsZero() || (!metrics.Earliest.IsZero() && metrics.Earliest.Before(start)) {
+			start = metrics.Earliest
+		}
+		if metrics.End.After(end) {
+			end = metrics.End
+		}
+		summary = append(summary, endpointName(endpoints[i])+": P99 "+formatMs(durationToMs(metrics.Latencies.P99))+
+			", "+strconv.FormatUint(metrics.Requests, 10)+" requests, "+formatPercent(metrics.Success)+" success")
+	}
+	// Without a single request, there's no time range to annotate
+	if start.IsZero() {
+		log.Print("No endpoint produced results, skipping the Grafana annotation")
+		return nil
+	}
+
+	annotation := grafanaAnnotation{
+		DashboardUID: grafanaSettings.DashboardUID,
+		PanelID:      grafanaSettings.PanelID,
+		Time:         start.UnixNano() / int64(time.Millisecond),
+		TimeEnd:      end.UnixNano() / int64(time.Millisecond),
+		Tags:         append([]string{"rtapi"}, grafanaSettings.Tags...),
+		Text:         "rtapi run\n" + strings.Join(summary, "\n"),
+	}
+	body, err := json.Marshal(annotation)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+grafanaSettings.Token)
+	_, err = postWithRetry(ctx, strings.TrimSuffix(grafanaSettings.Url, "/")+"/api/annotations", header, body)
+	return err
+}
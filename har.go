@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Request struct {
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Headers  []harHeader `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harFilter struct {
+	URL          string
+	ContentTypes []string
+}
+
+// Headers recomputed by the HTTP client which must not be replayed as captured
+var skippedHARHeaders = map[string]bool{
+	"Content-Length": true,
+	"Connection":     true,
+}
+
+// Extract the requests captured in a HAR file as endpoints, each of them
+// queried with the given query parameters. Identical requests are only
+// replayed once
+func parseEndpointsHAR(file string, filter harFilter, query endpointQuery) []endpointDetails {
+	byteValue, err := ioutil.ReadFile(file)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	var har harFile
+	err = json.Unmarshal(byteValue, &har)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+
+	var urlPattern *regexp.Regexp
+	if filter.URL != "" {
+		urlPattern, err = regexp.Compile(filter.URL)
+		if err != nil {
+			fatal(exitReasonInvalidInput, err)
+		}
+	}
+
+	var endpoints []endpointDetails
+	seen := make(map[string]bool)
+	for _, entry := range har.Log.Entries {
+		if urlPattern != nil && !urlPattern.MatchString(entry.Request.URL) {
+			continue
+		}
+		if !matchesContentType(entry.Response.Content.MimeType, filter.ContentTypes) {
+			continue
+		}
+
+		target := endpointTarget{
+			Method: entry.Request.Method,
+			URL:    entry.Request.URL,
+			Header: make(http.Header),
+		}
+		for _, header := range entry.Request.Headers {
+			// HTTP/2 pseudo headers such as :authority can't be sent as regular headers
+			if strings.HasPrefix(header.Name, ":") || skippedHARHeaders[http.CanonicalHeaderKey(header.Name)] {
+				continue
+			}
+			target.Header.Add(header.Name, header.Value)
+		}
+		if entry.Request.PostData != nil {
+			target.Body = entry.Request.PostData.Text
+			if target.Header.Get("Content-Type") == "" && entry.Request.PostData.MimeType != "" {
+				target.Header.Set("Content-Type", entry.Request.PostData.MimeType)
+			}
+		}
+
+		key := target.Method + " " + target.URL + "\n" + target.Body
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		endpoints = append(endpoints, endpointDetails{Target: target, Query: query})
+	}
+	if len(endpoints) == 0 {
+		fatal(exitReasonInvalidInput, "No requests found in HAR file "+file)
+	}
+	return endpoints
+}
+
+// Check whether a mime type (ignoring any parameters such as the charset)
+// is one of the accepted content types, accepting everything if none are given
+func matchesContentType(mimeType string, contentTypes []string) bool {
+	if len(contentTypes) == 0 {
+		return true
+	}
+	mimeType = strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0])
+	for _, contentType := range contentTypes {
+		if strings.EqualFold(mimeType, strings.TrimSpace(contentType)) {
+			return true
+		}
+	}
+	return false
+}
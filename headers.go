@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type headerField struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// Build the request header of a target, adding its header list in order
+// on top of its header map so repeated keys keep their values and order
+func requestHeader(target endpointTarget) http.Header {
+	header := target.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	for _, field := range target.HeaderList {
+		header.Add(field.Name, field.Value)
+	}
+	return header
+}
+
+// Headers which are expected to change between runs and are therefore
+// left out of the baseline comparison
+var volatileHeaders = map[string]bool{
+	"Age":            true,
+	"Content-Length": true,
+	"Date":           true,
+	"Expires":        true,
+	"Last-Modified":  true,
+	"Set-Cookie":     true,
+	"X-Request-Id":   true,
+}
+
+// Compare the response headers of a baseline run against the current ones and
+// describe every header that has been removed, changed or added
+func diffHeaders(baseline http.Header, current http.Header) []string {
+	var diff []string
+	for _, key := range sortedHeaderKeys(baseline) {
+		if volatileHeaders[key] {
+			continue
+		}
+		values, ok := current[key]
+		if !ok {
+			diff = append(diff, "removed "+key+": "+strings.Join(baseline[key], ", "))
+		} else if !reflect.DeepEqual(baseline[key], values) {
+			diff = append(diff, "changed "+key+": "+strings.Join(baseline[key], ", ")+" -> "+strings.Join(values, ", "))
+		}
+	}
+	for _, key := range sortedHeaderKeys(current) {
+		if volatileHeaders[key] {
+			continue
+		}
+		if _, ok := baseline[key]; !ok {
+			diff = append(diff, "added "+key+": "+strings.Join(current[key], ", "))
+		}
+	}
+	return diff
+}
+
+func sortedHeaderKeys(header http.Header) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Print the header differences of each endpoint to stderr so that they don't
+// get mixed with any json output written to stdout
+func printHeaderDiff(endpoints []endpointDetails, baseline []endpointDetails) {
+	for i := range endpoints {
+		var previous *endpointDetails
+		for j := range baseline {
+			if baseline[j].Target.URL == endpoints[i].Target.URL {
+				previous = &baseline[j]
+				break
+			}
+		}
+		if previous == nil {
+			os.Stderr.Write([]byte("No baseline headers found for " + endpoints[i].Target.URL + "\n"))
+			continue
+		}
+		diff := diffHeaders(previous.ResponseHeaders, endpoints[i].ResponseHeaders)
+		if len(diff) == 0 {
+			os.Stderr.Write([]byte("Response headers unchanged for " + endpoints[i].Target.URL + "\n"))
+			continue
+		}
+		os.Stderr.Write([]byte("Response headers changed for " + endpoints[i].Target.URL + ":\n"))
+		for j := range diff {
+			os.Stderr.Write([]byte("  " + diff[j] + "\n"))
+		}
+	}
+}
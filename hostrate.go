@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Rates and requests of the endpoints sharing a host
+type hostLoad struct {
+	Host      string
+	Endpoints []string
+	// Highest rate any of the endpoints is paced at in requests per second.
+	// Endpoints are queried one after another, so this is the most the host
+	// receives at once rather than the sum of their rates
+	PeakRate float64
+	Requests uint64
+}
+
+// The highest rate an endpoint's pacer sends requests at, or 0 if it isn't
+// paced at all, like a worker ramp
+func peakRate(query endpointQuery) float64 {
+	if query.WorkerRamp {
+		return 0
+	}
+	if query.Pacer.Type == "sine" {
+		return float64(query.RequestRate + query.Pacer.Amplitude)
+	}
+	return float64(query.RequestRate)
+}
+
+// Group the endpoints by host, in order of first appearance
+func hostLoads(endpoints []endpointDetails) []hostLoad {
+	var loads []hostLoad
+	index := make(map[string]int)
+	for i := range endpoints {
+		host := endpoints[i].Target.URL
+		if u, err := url.Parse(endpoints[i].Target.URL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		j, ok := index[host]
+		if !ok {
+			j = len(loads)
+			index[host] = j
+			loads = append(loads, hostLoad{Host: host})
+		}
+		loads[j].Endpoints = append(loads[j].Endpoints, endpointName(endpoints[i]))
+		if rate := peakRate(endpoints[i].Query); rate > loads[j].PeakRate {
+			loads[j].PeakRate = rate
+		}
+		loads[j].Requests += endpoints[i].Metrics.Requests
+	}
+	return loads
+}
+
+// List the hosts whose endpoints would be paced above the given rate
+func hostsOverRate(endpoints []endpointDetails, maxRate float64) []string {
+	var hosts []string
+	for _, load := range hostLoads(endpoints) {
+		if load.PeakRate > maxRate {
+			hosts = append(hosts, load.Host+" ("+formatRate(load.PeakRate)+")")
+		}
+	}
+	return hosts
+}
+
+func printHostLoads(endpoints []endpointDetails) {
+	os.Stdout.Write([]byte("Load per host:\n"))
+	os.Stdout.Write([]byte("  Host\tEndpoints\tPeak rate\tRequests\n"))
+	for _, load := range hostLoads(endpoints) {
+		os.Stdout.Write([]byte("  " + load.Host + "\t" + strings.Join(load.Endpoints, ", ") + "\t" +
+			formatRate(load.PeakRate) + "\t" + strconv.FormatUint(load.Requests, 10) + "\n"))
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Length of each unit latencies can be written in
+var latencyUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// Paths of the latencies and durations in an endpoint's JSON, "*" standing
+// for any key or array index
+var latencyPaths = [][]string{
+	{"metrics", "latencies", "*"},
+	{"metrics", "duration"},
+	{"metrics", "wait"},
+	{"latency_stddev"},
+	{"percentile_intervals", "*", "latency"},
+	{"percentile_intervals", "*", "lower"},
+	{"percentile_intervals", "*", "upper"},
+	{"status_classes", "*", "latencies", "*"},
+	{"sla", "*", "latency"},
+	{"ramp_stages", "*", "99th"},
+	{"timeline", "*", "offset"},
+	{"timeline", "*", "latency"},
+	{"warmup", "duration"},
+	{"rate_limited", "paused"},
+}
+
+// Path of the object whose keys are latencies, the histogram's bucket bounds
+var latencyKeysPath = []string{"histogram"}
+
+// Unit and number of decimals of the latencies in the JSON outputs. The
+// default is vegeta's raw nanosecond integers
+type latencyFormat struct {
+	Unit string
+	// Number of decimals, or -1 for as many as needed
+	Precision int
+}
+
+func (format latencyFormat) raw() bool {
+	return (format.Unit == "" || format.Unit == "ns") && format.Precision < 0
+}
+
+// Return the value to encode for an endpoint, with its latencies converted
+// to the format unless it's the raw default. The unit is recorded in the
+// endpoint's latency_unit, for the JSON to be read back
+func (format latencyFormat) apply(endpoint endpointDetails) (interface{}, error) {
+	if format.raw() {
+		return endpoint, nil
+	}
+	endpoint.LatencyUnit = format.Unit
+	if endpoint.LatencyUnit == "" {
+		endpoint.LatencyUnit = "ns"
+	}
+	jsonInfo, err := json.Marshal(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := convertLatencies(jsonInfo, func(nanoseconds float64) string {
+		converted := nanoseconds / float64(latencyUnits[format.Unit])
+		return strconv.FormatFloat(converted, 'f', format.Precision, 64)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(converted), nil
+}
+
+// Convert the latencies of JSON endpoints written in another unit back to
+// vegeta's nanoseconds, so they can be decoded
+func readLatencies(data []byte) ([]byte, error) {
+	var endpoints []json.RawMessage
+	if json.Unmarshal(data, &endpoints) != nil {
+		// Not an array, which decoding the endpoints will report
+		return data, nil
+	}
+	converted := false
+	for i := range endpoints {
+		var format struct {
+			LatencyUnit string `json:"latency_unit"`
+		}
+		if json.Unmarshal(endpoints[i], &format) != nil || format.LatencyUnit == "" {
+			continue
+		}
+		unit, ok := latencyUnits[format.LatencyUnit]
+		if !ok {
+			return nil, errors.New("unknown latency_unit " + format.LatencyUnit)
+		}
+		endpoint, err := convertLatencies(endpoints[i], func(value float64) string {
+			return strconv.FormatFloat(math.Round(value*float64(unit)), 'f', 0, 64)
+		})
+		if err != nil {
+			return nil, err
+		}
+		endpoints[i] = endpoint
+		converted = true
+	}
+	if !converted {
+		return data, nil
+	}
+	return json.Marshal(endpoints)
+}
+
+// Rewrite the latencies of an endpoint's JSON with convert, keeping its
+// fields in the order they were encoded in
+func convertLatencies(data []byte, convert func(float64) string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var out bytes.Buffer
+	if err := rewriteLatencies(decoder, &out, nil, convert); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func rewriteLatencies(decoder *json.Decoder, out *bytes.Buffer, path []string, convert func(float64) string) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '[' {
+			out.WriteByte('[')
+			for i := 0; decoder.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				if err := rewriteLatencies(decoder, out, append(path[:len(path):len(path)], "*"), convert); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(']')
+		} else {
+			out.WriteByte('{')
+			for i := 0; decoder.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				token, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				key := token.(string)
+				written := key
+				if matchesPath(path, latencyKeysPath) {
+					if written, err = convertLatency(json.Number(key), convert); err != nil {
+						return err
+					}
+				}
+				out.WriteString(strconv.Quote(written) + ":")
+				if err := rewriteLatencies(decoder, out, append(path[:len(path):len(path)], key), convert); err != nil {
+					return err
+				}
+			}
+			out.WriteByte('}')
+		}
+		// The closing delimiter
+		_, err = decoder.Token()
+		return err
+	case json.Number:
+		value := token.String()
+		for _, latencyPath := range latencyPaths {
+			if matchesPath(path, latencyPath) {
+				if value, err = convertLatency(token, convert); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		out.WriteString(value)
+		return nil
+	default:
+		value, err := json.Marshal(token)
+		if err != nil {
+			return err
+		}
+		out.Write(value)
+		return nil
+	}
+}
+
+func matchesPath(path []string, pattern []string) bool {
+	if len(path) != len(pattern) {
+		return false
+	}
+	for i := range path {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func convertLatency(latency json.Number, convert func(float64) string) (string, error) {
+	value, err := latency.Float64()
+	if err != nil {
+		return "", errors.New("latency " + latency.String() + " is not a number")
+	}
+	return convert(value), nil
+}
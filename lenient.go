@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// An endpoint of the input skipped by --lenient
+type skippedEntry struct {
+	// Position of the entry in the input, from 1
+	Index int
+	// Name of the entry, if it could be decoded, and its line, only known for YAML
+	Name  string
+	Line  int
+	Error string
+}
+
+func (entry skippedEntry) String() string {
+	position := "entry " + strconv.Itoa(entry.Index)
+	if entry.Name != "" {
+		position += " \"" + entry.Name + "\""
+	}
+	if entry.Line > 0 {
+		position += " (line " + strconv.Itoa(entry.Line) + ")"
+	}
+	return position + ": " + entry.Error
+}
+
+// Parse a JSON or YAML endpoints file one endpoint at a time, skipping the
+// malformed ones instead of failing the whole file
+func parseEndpointsFileLenient(file string, isYAML bool) ([]endpointDetails, []skippedEntry) {
+	byteValue, err := ioutil.ReadFile(file)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	if isYAML {
+		return parseEndpointsYAMLLenient(byteValue)
+	}
+	return parseEndpointsJSONLenient(byteValue)
+}
+
+func parseEndpointsJSONLenient(byteValue []byte) ([]endpointDetails, []skippedEntry) {
+	// The file itself must still be an array
+	var entries []json.RawMessage
+	if err := json.Unmarshal(byteValue, &entries); err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	var endpoints []endpointDetails
+	var skipped []skippedEntry
+	for i, entry := range entries {
+		var endpoint endpointDetails
+		err := json.Unmarshal(entry, &endpoint)
+		if err == nil {
+			err = checkEndpoint(endpoint)
+		}
+		if err != nil {
+			skipped = append(skipped, skipEntry(skippedEntry{Index: i + 1, Name: endpoint.Name, Error: err.Error()}))
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, skipped
+}
+
+func parseEndpointsYAMLLenient(byteValue []byte) ([]endpointDetails, []skippedEntry) {
+	var entries []yaml.Node
+	if err := yaml.Unmarshal(byteValue, &entries); err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	var endpoints []endpointDetails
+	var skipped []skippedEntry
+	for i := range entries {
+		var endpoint endpointDetails
+		err := entries[i].Decode(&endpoint)
+		if err == nil {
+			err = checkEndpoint(endpoint)
+		}
+		if err != nil {
+			skipped = append(skipped, skipEntry(skippedEntry{Index: i + 1, Name: endpoint.Name, Line: entries[i].Line, Error: err.Error()}))
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, skipped
+}
+
+func skipEntry(entry skippedEntry) skippedEntry {
+	log.Print("Skipping malformed ", entry)
+	return entry
+}
+
+// Summarize the skipped entries after all outputs, so they aren't lost in
+// the progress output
+func printSkippedEntries(skipped []skippedEntry) {
+	os.Stderr.Write([]byte("Skipped " + strconv.Itoa(len(skipped)) + " malformed endpoints:\n"))
+	for _, entry := range skipped {
+		os.Stderr.Write([]byte("  " + entry.String() + "\n"))
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"math"
+	"strconv"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Count the requests which failed, with or without a response
+func countErrors(metrics vegeta.Metrics) uint64 {
+	return metrics.Requests - uint64(math.Round(metrics.Success*float64(metrics.Requests)))
+}
+
+// Whether an endpoint failed more requests than its absolute maximum, if one was set
+func tooManyErrors(endpoint endpointDetails) bool {
+	return endpoint.MaxErrors != nil && endpoint.Errors > uint64(*endpoint.MaxErrors)
+}
+
+func maxErrorsWarning(endpoint endpointDetails) string {
+	share := float64(endpoint.Errors) / float64(endpoint.Metrics.Requests)
+	return "FAILED: " + endpointName(endpoint) + " may fail at most " + strconv.Itoa(*endpoint.MaxErrors) +
+		" requests but " + strconv.FormatUint(endpoint.Errors, 10) + " (" + formatPercent(share) + ") failed"
+}
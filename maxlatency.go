@@ -0,0 +1,26 @@
+package main
+
+import (
+	"time"
+)
+
+// Longest latency any single request of an endpoint may take, if one was set
+func maxLatency(endpoint endpointDetails) (time.Duration, error) {
+	if endpoint.MaxLatency == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(endpoint.MaxLatency)
+}
+
+// Whether any request of an endpoint took longer than its maximum latency,
+// which percentile thresholds let through
+func exceedsMaxLatency(endpoint endpointDetails) bool {
+	limit, err := maxLatency(endpoint)
+	return err == nil && limit > 0 && endpoint.Metrics.Latencies.Max > limit
+}
+
+func maxLatencyWarning(endpoint endpointDetails) string {
+	limit, _ := maxLatency(endpoint)
+	return "FAILED: no request to " + endpointName(endpoint) + " may take longer than " + formatMs(durationToMs(limit)) +
+		" but the slowest took " + formatMs(durationToMs(endpoint.Metrics.Latencies.Max))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+var httpMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// Replace the method of every endpoint for this run only, e.g. HEAD to check
+// the endpoints exist without transferring their bodies
+func overrideMethod(endpoints []endpointDetails, method string) {
+	method = strings.ToUpper(method)
+	if !httpMethods[method] {
+		fatal(exitReasonInvalidInput, "Unknown HTTP method: "+method)
+	}
+	for i := range endpoints {
+		endpoints[i].Target.Method = method
+	}
+}
@@ -0,0 +1,14 @@
+package main
+
+// The team owning an endpoint and how to reach it, e.g. "payments
+// (#payments-oncall)", or an empty string if neither was set
+func endpointOwner(endpoint endpointDetails) string {
+	switch {
+	case endpoint.Owner != "" && endpoint.Contact != "":
+		return endpoint.Owner + " (" + endpoint.Contact + ")"
+	case endpoint.Owner != "":
+		return endpoint.Owner
+	default:
+		return endpoint.Contact
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+type endpointPacer struct {
+	Type      string `json:"type" yaml:"type"`
+	Amplitude int    `json:"amplitude" yaml:"amplitude"`
+	Period    string `json:"period" yaml:"period"`
+	On        string `json:"on" yaml:"on"`
+	Off       string `json:"off" yaml:"off"`
+}
+
+// Build the vegeta pacer described by the query parameters, falling back to
+// a constant rate if no pacer type has been specified
+func newPacer(query endpointQuery) vegeta.Pacer {
+	if err := checkPacer(query); err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	rate := vegeta.Rate{
+		Freq: query.RequestRate,
+		Per:  time.Second,
+	}
+	switch query.Pacer.Type {
+	case "sine":
+		period, _ := time.ParseDuration(query.Pacer.Period)
+		return vegeta.SinePacer{
+			Period:  period,
+			Mean:    rate,
+			Amp:     vegeta.Rate{Freq: query.Pacer.Amplitude, Per: time.Second},
+			StartAt: vegeta.MeanUp,
+		}
+	case "burst":
+		on, _ := time.ParseDuration(query.Pacer.On)
+		off, _ := time.ParseDuration(query.Pacer.Off)
+		return burstPacer{On: on, Off: off, Peak: rate}
+	}
+	return rate
+}
+
+// Reject the pacer parameters newPacer can't build a pacer from
+func checkPacer(query endpointQuery) error {
+	switch query.Pacer.Type {
+	case "", "constant":
+	case "sine":
+		if _, err := time.ParseDuration(query.Pacer.Period); err != nil {
+			return err
+		}
+		if query.Pacer.Amplitude < 0 || query.Pacer.Amplitude >= query.RequestRate {
+			return errors.New("sine pacer amplitude must not be negative and must be lower than the request rate")
+		}
+	case "burst":
+		on, err := time.ParseDuration(query.Pacer.On)
+		if err != nil {
+			return err
+		}
+		off, err := time.ParseDuration(query.Pacer.Off)
+		if err != nil {
+			return err
+		}
+		if on <= 0 || off < 0 {
+			return errors.New("burst pacer on period must be positive and off period must not be negative")
+		}
+	default:
+		return errors.New("unknown pacer type: " + query.Pacer.Type)
+	}
+	return nil
+}
+
+// burstPacer sends hits at a constant rate during the on period and
+// stays silent during the off period, repeating for the whole attack
+type burstPacer struct {
+	On   time.Duration
+	Off  time.Duration
+	Peak vegeta.Rate
+}
+
+var _ vegeta.Pacer = burstPacer{}
+
+// Pace determines the length of time to sleep until the next hit is sent
+func (bp burstPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	if bp.Peak.Freq <= 0 || bp.Peak.Per <= 0 {
+		return 0, true
+	}
+	hitsPerCycle := uint64(math.Max(1, float64(bp.Peak.Freq)*float64(bp.On)/float64(bp.Peak.Per)))
+	interval := bp.On / time.Duration(hitsPerCycle)
+	cycle := bp.On + bp.Off
+
+	next := time.Duration(hits/hitsPerCycle)*cycle + time.Duration(hits%hitsPerCycle)*interval
+	if next <= elapsed {
+		// Running behind, send next hit immediately
+		return 0, false
+	}
+	return next - elapsed, false
+}
+
+// Rate returns the instantaneous hit rate (per second) at the given elapsed duration
+func (bp burstPacer) Rate(elapsed time.Duration) float64 {
+	if elapsed%(bp.On+bp.Off) >= bp.On {
+		return 0
+	}
+	return float64(bp.Peak.Freq) / bp.Peak.Per.Seconds()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Percentiles reported by vegeta's text reporter and the latency table
+var defaultPercentiles = []float64{50, 90, 95, 99}
+
+// Parse a comma separated list of percentiles, e.g. "50,75,90,95,99,99.9"
+func parsePercentiles(list string) []float64 {
+	var percentiles []float64
+	for _, field := range strings.Split(list, ",") {
+		percentile, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err == nil && (percentile <= 0 || percentile > 100) {
+			err = errors.New("must be greater than 0 and at most 100")
+		}
+		if err != nil {
+			fatal(exitReasonInvalidInput, "Invalid percentile "+strings.TrimSpace(field)+": "+err.Error())
+		}
+		percentiles = append(percentiles, percentile)
+	}
+	sort.Float64s(percentiles)
+	return percentiles
+}
+
+func formatPercentile(percentile float64) string {
+	return strconv.FormatFloat(percentile, 'f', -1, 64)
+}
+
+// Latency of the endpoint at the given percentile. Other percentiles than
+// vegeta's fixed ones are estimated from the latencies of all the requests,
+// which are only known right after the run
+func latencyAtPercentile(metrics vegeta.Metrics, percentile float64) time.Duration {
+	switch percentile {
+	case 50:
+		return metrics.Latencies.P50
+	case 90:
+		return metrics.Latencies.P90
+	case 95:
+		return metrics.Latencies.P95
+	case 99:
+		return metrics.Latencies.P99
+	}
+	return metrics.Latencies.Quantile(percentile / 100)
+}
+
+// Same report as vegeta's text reporter, with the latencies at the given
+// percentiles instead of its fixed ones
+func newPercentilesTextReporter(m *vegeta.Metrics, percentiles []float64) vegeta.Reporter {
+	return func(w io.Writer) (err error) {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.StripEscape)
+		names := []string{"min", "mean"}
+		latencies := []string{roundDuration(m.Latencies.Min).String(), roundDuration(m.Latencies.Mean).String()}
+		for _, percentile := range percentiles {
+			names = append(names, formatPercentile(percentile))
+			latencies = append(latencies, roundDuration(latencyAtPercentile(*m, percentile)).String())
+		}
+		names = append(names, "max")
+		latencies = append(latencies, roundDuration(m.Latencies.Max).String())
+
+		if _, err = fmt.Fprintf(tw, "Requests\t[total, rate, throughput]\t%d, %.2f, %.2f\n"+
+			"Duration\t[total, attack, wait]\t%s, %s, %s\n"+
+			"Latencies\t[%s]\t%s\n"+
+			"Bytes In\t[total, mean]\t%d, %.2f\n"+
+			"Bytes Out\t[total, mean]\t%d, %.2f\n"+
+			"Success\t[ratio]\t%.2f%%\n"+
+			"Status Codes\t[code:count]\t",
+			m.Requests, m.Rate, m.Throughput,
+			roundDuration(m.Duration+m.Wait), roundDuration(m.Duration), roundDuration(m.Wait),
+			strings.Join(names, ", "), strings.Join(latencies, ", "),
+			m.BytesIn.Total, m.BytesIn.Mean,
+			m.BytesOut.Total, m.BytesOut.Mean,
+			m.Success*100,
+		); err != nil {
+			return err
+		}
+		codes := make([]string, 0, len(m.StatusCodes))
+		for code := range m.StatusCodes {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			if _, err = fmt.Fprintf(tw, "%s:%d  ", code, m.StatusCodes[code]); err != nil {
+				return err
+			}
+		}
+		if _, err = fmt.Fprintln(tw, "\nError Set:"); err != nil {
+			return err
+		}
+		for _, e := range m.Errors {
+			if _, err = fmt.Fprintln(tw, e); err != nil {
+				return err
+			}
+		}
+		return tw.Flush()
+	}
+}
+
+// Round a duration to the unit below its largest one, like vegeta's reports
+func roundDuration(d time.Duration) time.Duration {
+	units := []time.Duration{time.Hour, time.Minute, time.Second, time.Millisecond, time.Microsecond, time.Nanosecond}
+	for i, unit := range units {
+		if d >= unit && i < len(units)-1 {
+			return d.Round(units[i+1])
+		}
+	}
+	return d
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strconv"
+	"sync"
+)
+
+type pipeliningInfo struct {
+	// Requested number of requests in flight per connection
+	Depth int `json:"depth" yaml:"depth"`
+	// Most requests ever sent on a connection before the response to the
+	// first of them was read
+	MaxInFlight int `json:"max_in_flight" yaml:"max_in_flight"`
+	// Whether any request was sent before the previous response was read
+	Achieved bool `json:"achieved" yaml:"achieved"`
+}
+
+var errPipelineBroken = errors.New("pipelined connection closed before the response")
+
+// HTTP/1.1 transport sending up to Depth requests on a connection before
+// reading their responses, in order. Go's standard transport never pipelines,
+// waiting for each response before sending the next request on a connection.
+// Connections are dialed with the dialer and TLS configuration of the wrapped
+// transport, up to its MaxIdleConnsPerHost per host, which vegeta sets from
+// the endpoint's connections. Proxies aren't supported
+type pipeliningTransport struct {
+	transport *http.Transport
+	depth     int
+
+	mu        sync.Mutex
+	available *sync.Cond
+	// Usable connections per host, and every connection ever dialed
+	conns       map[string][]*pipelinedConn
+	dialed      []*pipelinedConn
+	maxInFlight int
+}
+
+func newPipeliningTransport(transport *http.Transport, depth int) *pipeliningTransport {
+	t := &pipeliningTransport{transport: transport, depth: depth, conns: make(map[string][]*pipelinedConn)}
+	t.available = sync.NewCond(&t.mu)
+	return t
+}
+
+// A connection with the requests sent on it and not answered yet, in order
+type pipelinedConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// Held while queuing a request and writing it, so requests are queued
+	// in the order they're written
+	writeMu sync.Mutex
+	writer  *bufio.Writer
+	pending chan *pipelinedRequest
+	// Guarded by the transport's mutex
+	inFlight int
+	broken   bool
+}
+
+type pipelinedRequest struct {
+	req      *http.Request
+	response chan pipelinedResponse
+}
+
+type pipelinedResponse struct {
+	resp *http.Response
+	err  error
+}
+
+func (t *pipeliningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, errors.New("pipelining: unsupported scheme " + req.URL.Scheme)
+	}
+	pc, reused, err := t.acquire(req)
+	if err != nil {
+		return nil, err
+	}
+	if trace := httptrace.ContextClientTrace(req.Context()); trace != nil && trace.GotConn != nil {
+		trace.GotConn(httptrace.GotConnInfo{Conn: pc.conn, Reused: reused})
+	}
+	pending := &pipelinedRequest{req: req, response: make(chan pipelinedResponse, 1)}
+	pc.writeMu.Lock()
+	pc.pending <- pending
+	err = req.Write(pc.writer)
+	if err == nil {
+		err = pc.writer.Flush()
+	}
+	pc.writeMu.Unlock()
+	// The reader fails the request once the connection is closed
+	if err != nil {
+		pc.conn.Close()
+	}
+	select {
+	case response := <-pending.response:
+		return response.resp, response.err
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+// Pick the least busy connection to the request's host with room for one more
+// request, dialing a new one only once all of them are full and waiting for
+// room once the connection limit is reached. The request is counted in flight
+// on the returned connection
+func (t *pipeliningTransport) acquire(req *http.Request) (*pipelinedConn, bool, error) {
+	host := canonicalAddr(req)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		var best *pipelinedConn
+		var conns []*pipelinedConn
+		for _, pc := range t.conns[host] {
+			if pc.broken {
+				continue
+			}
+			conns = append(conns, pc)
+			if pc.inFlight < t.depth && (best == nil || pc.inFlight < best.inFlight) {
+				best = pc
+			}
+		}
+		t.conns[host] = conns
+		if best != nil {
+			t.use(best)
+			return best, true, nil
+		}
+		if len(conns) < t.maxConns() {
+			pc, err := t.dial(req, host)
+			if err != nil {
+				return nil, false, err
+			}
+			t.conns[host] = append(t.conns[host], pc)
+			t.dialed = append(t.dialed, pc)
+			t.use(pc)
+			return pc, false, nil
+		}
+		t.available.Wait()
+	}
+}
+
+func (t *pipeliningTransport) maxConns() int {
+	if t.transport.MaxIdleConnsPerHost > 0 {
+		return t.transport.MaxIdleConnsPerHost
+	}
+	return http.DefaultMaxIdleConnsPerHost
+}
+
+func (t *pipeliningTransport) use(pc *pipelinedConn) {
+	pc.inFlight++
+	if pc.inFlight > t.maxInFlight {
+		t.maxInFlight = pc.inFlight
+	}
+}
+
+// Dial a connection while holding the transport's lock, so the connection
+// limit holds. Pipelined connections are few and long lived, so this rarely
+// blocks the others
+func (t *pipeliningTransport) dial(req *http.Request, host string) (*pipelinedConn, error) {
+	dial := net.Dial
+	if t.transport.Dial != nil {
+		dial = t.transport.Dial
+	}
+	conn, err := dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Scheme == "https" {
+		config := &tls.Config{}
+		if t.transport.TLSClientConfig != nil {
+			config = t.transport.TLSClientConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = req.URL.Hostname()
+		}
+		// Pipelining is an HTTP/1.1 feature
+		config.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	pc := &pipelinedConn{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		writer:  bufio.NewWriter(conn),
+		pending: make(chan *pipelinedRequest, t.depth),
+	}
+	go t.readResponses(pc)
+	return pc, nil
+}
+
+// Read the responses of a connection in the order their requests were sent.
+// Bodies are read in full so the next response can be read, which vegeta
+// does anyway. Once the connection fails or the server closes it, the
+// requests still waiting for a response fail
+func (t *pipeliningTransport) readResponses(pc *pipelinedConn) {
+	var err error
+	for pending := range pc.pending {
+		if err != nil {
+			t.done(pc, pending, nil, errPipelineBroken)
+			continue
+		}
+		var resp *http.Response
+		resp, err = http.ReadResponse(pc.reader, pending.req)
+		if err == nil {
+			var body []byte
+			body, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		if err != nil {
+			t.done(pc, pending, nil, err)
+			continue
+		}
+		t.done(pc, pending, resp, nil)
+		if resp.Close {
+			err = errPipelineBroken
+		}
+	}
+}
+
+// Hand a response to its request and make room for another one, retiring the
+// connection once it's broken
+func (t *pipeliningTransport) done(pc *pipelinedConn, pending *pipelinedRequest, resp *http.Response, err error) {
+	pending.response <- pipelinedResponse{resp: resp, err: err}
+	t.mu.Lock()
+	pc.inFlight--
+	if err != nil || (resp != nil && resp.Close) {
+		if !pc.broken {
+			pc.broken = true
+			pc.conn.Close()
+		}
+	}
+	t.mu.Unlock()
+	t.available.Broadcast()
+}
+
+// Close every connection once the attack is over, ending their readers
+func (t *pipeliningTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, pc := range t.dialed {
+		pc.conn.Close()
+		close(pc.pending)
+	}
+	t.dialed = nil
+	t.conns = make(map[string][]*pipelinedConn)
+}
+
+func (t *pipeliningTransport) Result() *pipeliningInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &pipeliningInfo{Depth: t.depth, MaxInFlight: t.maxInFlight, Achieved: t.maxInFlight > 1}
+}
+
+// Address of the request's host with the default port of its scheme
+func canonicalAddr(req *http.Request) string {
+	port := req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(req.URL.Hostname(), port)
+}
+
+func printPipelining(info *pipeliningInfo) {
+	if info.Achieved {
+		os.Stdout.Write([]byte("Pipelining: up to " + strconv.Itoa(info.MaxInFlight) + " requests in flight per connection, of a depth of " +
+			strconv.Itoa(info.Depth) + "\n"))
+	} else {
+		os.Stdout.Write([]byte("Pipelining: not achieved, every request waited for the previous response (depth " +
+			strconv.Itoa(info.Depth) + ")\n"))
+	}
+}
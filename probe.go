@@ -0,0 +1,26 @@
+package main
+
+import (
+	"math"
+	"strconv"
+)
+
+// Estimate the maximum sustainable rate of an endpoint with a short attack
+// that isn't capped by any request rate, then lower the endpoint's request
+// rate to the given percentage of it
+func applyRatePercent(endpoint *endpointDetails, percent float64, probeDuration string) {
+	probe := *endpoint
+	probe.Query.RequestRate = 0
+	probe.Query.Pacer = endpointPacer{}
+	probe.Query.Duration = probeDuration
+	probe.ResponseHeaders = nil
+	queryAPI(&probe, queryOptions{})
+
+	endpoint.DiscoveredMaxRate = probe.Metrics.Throughput
+	rate := int(math.Round(probe.Metrics.Throughput * percent / 100))
+	if rate < 1 {
+		fatal(exitReasonError, "Probing "+endpointName(*endpoint)+" found no sustainable rate ("+
+			strconv.FormatFloat(probe.Metrics.Throughput, 'f', 2, 64)+" successful requests/s)")
+	}
+	endpoint.Query.RequestRate = rate
+}
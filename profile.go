@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Named set of query parameters overriding those of every endpoint, so
+// standard tests are run the same way by everyone. Unset parameters keep the
+// endpoint's own value
+type queryProfile struct {
+	Duration    string         `json:"duration" yaml:"duration"`
+	RequestRate int            `json:"request_rate" yaml:"request_rate"`
+	Threads     uint64         `json:"threads" yaml:"threads"`
+	MaxThreads  uint64         `json:"max_threads" yaml:"max_threads"`
+	Connections int            `json:"connections" yaml:"connections"`
+	Pacer       *endpointPacer `json:"pacer" yaml:"pacer"`
+	WorkerRamp  bool           `json:"worker_ramp" yaml:"worker_ramp"`
+	RampSteps   int            `json:"ramp_steps" yaml:"ramp_steps"`
+}
+
+// Profiles available without a --profiles file
+var builtinProfiles = map[string]queryProfile{
+	// Quick check that the endpoints answer at all
+	"smoke": {Duration: "10s", RequestRate: 5},
+	// Steady, moderate load held long enough to reveal leaks and drifts
+	"soak": {Duration: "30m", RequestRate: 100, Pacer: &endpointPacer{Type: "constant"}},
+	// Bursts of high load, 10s every minute
+	"spike": {Duration: "5m", RequestRate: 500, Pacer: &endpointPacer{Type: "burst", On: "10s", Off: "50s"}},
+}
+
+// Look up a profile among the user defined profiles, which take precedence,
+// and the built-in ones
+func selectProfile(name string, userProfiles map[string]queryProfile) queryProfile {
+	profile, ok := userProfiles[name]
+	if !ok {
+		profile, ok = builtinProfiles[name]
+	}
+	if !ok {
+		fatal(exitReasonInvalidInput, "Unknown profile "+name+", expected one of "+strings.Join(profileNames(userProfiles), ", "))
+	}
+	if profile.Duration != "" {
+		if _, err := time.ParseDuration(profile.Duration); err != nil {
+			fatal(exitReasonInvalidInput, "Profile "+name+": "+err.Error())
+		}
+	}
+	return profile
+}
+
+func profileNames(userProfiles map[string]queryProfile) []string {
+	var names []string
+	for name := range builtinProfiles {
+		if _, ok := userProfiles[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range userProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Override the query parameters set by the profile
+func applyProfile(query *endpointQuery, profile queryProfile) {
+	if profile.Duration != "" {
+		query.Duration = profile.Duration
+	}
+	if profile.RequestRate > 0 {
+		query.RequestRate = profile.RequestRate
+	}
+	if profile.Threads > 0 {
+		query.Threads = profile.Threads
+	}
+	if profile.MaxThreads > 0 {
+		query.MaxThreads = profile.MaxThreads
+	}
+	if profile.Connections > 0 {
+		query.Connections = profile.Connections
+	}
+	if profile.Pacer != nil {
+		query.Pacer = *profile.Pacer
+	}
+	if profile.WorkerRamp {
+		query.WorkerRamp = true
+		query.RampSteps = profile.RampSteps
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+)
+
+// Build a transport speaking HTTP/2 over cleartext connections without
+// upgrading from HTTP/1.1 first (prior knowledge), for h2c services rejecting
+// HTTP/1.1. Connections are dialed with the dialer of the given transport,
+// like vegeta's H2C option, which can't be used as the client is wrapped
+func newH2CTransport(transport *http.Transport) *http2.Transport {
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return transport.Dial(network, addr)
+		},
+	}
+}
+
+// Count the protocol of every response, e.g. "HTTP/1.1" or "HTTP/2.0"
+type protocolCounter struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// Wrap a client so the protocol of every response it receives is counted,
+// after all of vegeta's transport options like connectionCounter.Client
+func (pc *protocolCounter) Client(client *http.Client) *http.Client {
+	counted := *client
+	counted.Transport = protocolTransport{RoundTripper: client.Transport, counter: pc}
+	return &counted
+}
+
+func (pc *protocolCounter) add(proto string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.counts == nil {
+		pc.counts = make(map[string]uint64)
+	}
+	pc.counts[proto]++
+}
+
+func (pc *protocolCounter) Result() map[string]uint64 {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.counts
+}
+
+type protocolTransport struct {
+	http.RoundTripper
+	counter *protocolCounter
+}
+
+func (t protocolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err == nil {
+		t.counter.add(resp.Proto)
+	}
+	return resp, err
+}
+
+func printProtocols(protocols map[string]uint64) {
+	names := make([]string, 0, len(protocols))
+	for proto := range protocols {
+		names = append(names, proto)
+	}
+	sort.Strings(names)
+	for i, proto := range names {
+		names[i] = proto + " (" + strconv.FormatUint(protocols[proto], 10) + ")"
+	}
+	os.Stdout.Write([]byte("Protocols: " + strings.Join(names, ", ") + "\n"))
+}
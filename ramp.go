@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Default maximum number of steps a worker ramp is split into
+const defaultRampSteps = 10
+
+type attackStage struct {
+	Pacer      vegeta.Pacer
+	Duration   time.Duration
+	Workers    uint64
+	MaxWorkers uint64
+}
+
+type rampStage struct {
+	Workers uint64        `json:"workers" yaml:"workers"`
+	Rate    float64       `json:"rate" yaml:"rate"`
+	P99     time.Duration `json:"99th" yaml:"99th"`
+}
+
+// Split the attack of an endpoint into stages. A worker ramp splits it into
+// equally long steps, each using a fixed number of workers sending requests as
+// fast as they can, growing from Threads to MaxThreads. Otherwise the whole
+// attack is a single stage at the configured rate
+func attackStages(query endpointQuery, pacer vegeta.Pacer, duration time.Duration) []attackStage {
+	if !query.WorkerRamp {
+		return []attackStage{{Pacer: pacer, Duration: duration, Workers: query.Threads, MaxWorkers: query.MaxThreads}}
+	}
+	if query.MaxThreads < query.Threads {
+		fatal(exitReasonInvalidInput, "A worker ramp needs max_threads to be at least threads")
+	}
+	steps := query.RampSteps
+	if steps <= 0 {
+		steps = defaultRampSteps
+		if spread := int(query.MaxThreads-query.Threads) + 1; spread < steps {
+			steps = spread
+		}
+	}
+	stages := make([]attackStage, steps)
+	for i := range stages {
+		workers := query.Threads
+		if steps > 1 {
+			workers += (query.MaxThreads - query.Threads) * uint64(i) / uint64(steps-1)
+		}
+		stages[i] = attackStage{
+			// A zero rate doesn't limit the workers at all
+			Pacer:      vegeta.Rate{},
+			Duration:   duration / time.Duration(steps),
+			Workers:    workers,
+			MaxWorkers: workers,
+		}
+	}
+	return stages
+}
+
+func printRampStages(stages []rampStage) {
+	os.Stdout.Write([]byte("Worker ramp:\n"))
+	os.Stdout.Write([]byte("  Workers\tRate (req/s)\tP99 (ms)\n"))
+	for _, stage := range stages {
+		os.Stdout.Write([]byte("  " + strconv.FormatUint(stage.Workers, 10) + "\t" +
+			strconv.FormatFloat(stage.Rate, 'f', 2, 64) + "\t" +
+			strconv.FormatFloat(durationToMs(stage.P99), 'f', 3, 64) + "\n"))
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// How long to pause when a rate limited response doesn't say how long to back off
+const defaultRetryAfter = time.Second
+
+type rateLimitInfo struct {
+	// Number and share of responses with a 429 status code
+	Responses uint64  `json:"responses" yaml:"responses"`
+	Share     float64 `json:"share" yaml:"share"`
+	// Last Retry-After header value received, if any
+	RetryAfter string `json:"retry_after,omitempty" yaml:"retry_after,omitempty"`
+	// Total time the attack was paused to honor the rate limit, if adapting to it
+	Paused time.Duration `json:"paused,omitempty" yaml:"paused,omitempty"`
+}
+
+// Count the rate limited responses of an attack
+type rateLimitDetector struct {
+	info rateLimitInfo
+}
+
+func (d *rateLimitDetector) Add(response *vegeta.Result) bool {
+	if response.Code != http.StatusTooManyRequests {
+		return false
+	}
+	d.info.Responses++
+	if retryAfter := response.Headers.Get("Retry-After"); retryAfter != "" {
+		d.info.RetryAfter = retryAfter
+	}
+	return true
+}
+
+// Describe the rate limiting of the attack if the share of rate limited
+// responses reached the given threshold (a ratio), or return nil otherwise
+func (d *rateLimitDetector) Result(requests uint64, threshold float64) *rateLimitInfo {
+	if requests == 0 || d.info.Responses == 0 {
+		return nil
+	}
+	d.info.Share = float64(d.info.Responses) / float64(requests)
+	if d.info.Share < threshold {
+		return nil
+	}
+	info := d.info
+	return &info
+}
+
+func rateLimitWarning(endpoint endpointDetails) string {
+	warning := "WARNING: " + endpointName(endpoint) + " rate limited the test, " + formatPercent(endpoint.RateLimited.Share) +
+		" of requests returned 429 Too Many Requests"
+	if endpoint.RateLimited.RetryAfter != "" {
+		warning += " (Retry-After: " + endpoint.RateLimited.RetryAfter + ")"
+	}
+	if endpoint.RateLimited.Paused > 0 {
+		warning += ", the test was paused for " + endpoint.RateLimited.Paused.String() + " to honor it"
+	}
+	return warning + ". Its latency doesn't reflect the requested load."
+}
+
+// Parse a Retry-After header value, given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return defaultRetryAfter
+}
+
+// rateLimitPacer wraps a pacer, pausing the attack when the target rate
+// limits it. The pacer being wrapped doesn't see the paused time elapse, so it
+// doesn't try to catch up on the hits it missed once the attack resumes
+type rateLimitPacer struct {
+	vegeta.Pacer
+	mu       sync.Mutex
+	resumeAt time.Duration
+	paused   time.Duration
+}
+
+func (p *rateLimitPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+	p.mu.Lock()
+	resumeAt, paused := p.resumeAt, p.paused
+	p.mu.Unlock()
+	if elapsed < resumeAt {
+		return resumeAt - elapsed, false
+	}
+	return p.Pacer.Pace(elapsed-paused, hits)
+}
+
+func (p *rateLimitPacer) Rate(elapsed time.Duration) float64 {
+	p.mu.Lock()
+	resumeAt, paused := p.resumeAt, p.paused
+	p.mu.Unlock()
+	if elapsed < resumeAt {
+		return 0
+	}
+	return p.Pacer.Rate(elapsed - paused)
+}
+
+// Pause the attack for the given duration from the given elapsed time
+func (p *rateLimitPacer) Pause(elapsed time.Duration, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	start := elapsed
+	if p.resumeAt > start {
+		start = p.resumeAt
+	}
+	if end := elapsed + duration; end > start {
+		p.paused += end - start
+		p.resumeAt = end
+	}
+}
+
+func (p *rateLimitPacer) Paused() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+type redirectInfo struct {
+	MaxRedirects int `json:"max_redirects" yaml:"max_redirects"`
+	// Requests which failed for reaching the maximum number of redirects,
+	// and for being redirected to a URL they had already visited
+	Capped uint64 `json:"capped" yaml:"capped"`
+	Loops  uint64 `json:"loops" yaml:"loops"`
+}
+
+// Limit the redirects followed by a client, failing requests caught in a
+// redirect loop as soon as they revisit a URL, and count the failures
+type redirectChecker struct {
+	Max    int
+	capped uint64
+	loops  uint64
+}
+
+// The maximum number of redirects to follow, vegeta's default if unset
+func maxRedirects(query endpointQuery) int {
+	if query.MaxRedirects == 0 {
+		return vegeta.DefaultRedirects
+	}
+	return query.MaxRedirects
+}
+
+// Reject a maximum number of redirects below -1, which would fail every
+// redirected request rather than not follow redirects
+func checkMaxRedirects(query endpointQuery) error {
+	if query.MaxRedirects < vegeta.NoFollow {
+		return errors.New("invalid max_redirects " + strconv.Itoa(query.MaxRedirects) + ", expected -1 or more")
+	}
+	return nil
+}
+
+func (rc *redirectChecker) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if rc.Max == vegeta.NoFollow {
+		return http.ErrUseLastResponse
+	}
+	for _, previous := range via {
+		if previous.URL.String() == req.URL.String() {
+			atomic.AddUint64(&rc.loops, 1)
+			return errors.New("redirect loop back to " + req.URL.String())
+		}
+	}
+	if len(via) > rc.Max {
+		atomic.AddUint64(&rc.capped, 1)
+		return errors.New("stopped after " + strconv.Itoa(rc.Max) + " redirects")
+	}
+	return nil
+}
+
+func (rc *redirectChecker) Result() *redirectInfo {
+	capped := atomic.LoadUint64(&rc.capped)
+	loops := atomic.LoadUint64(&rc.loops)
+	if capped == 0 && loops == 0 {
+		return nil
+	}
+	return &redirectInfo{MaxRedirects: rc.Max, Capped: capped, Loops: loops}
+}
+
+func redirectWarning(endpoint endpointDetails) string {
+	info := endpoint.Redirects
+	return "WARNING: " + strconv.FormatUint(info.Capped, 10) + " requests to " + endpointName(endpoint) +
+		" failed after reaching the maximum of " + strconv.Itoa(info.MaxRedirects) + " redirects and " +
+		strconv.FormatUint(info.Loops, 10) + " were caught in a redirect loop"
+}
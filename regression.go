@@ -0,0 +1,57 @@
+package main
+
+import "os"
+
+// An endpoint's latency at 99% in a baseline run and in the current one
+type regression struct {
+	Name     string
+	Baseline float64
+	Current  float64
+}
+
+// Growth of the latency at 99% as a ratio of the baseline
+func (r regression) Growth() float64 {
+	return r.Current/r.Baseline - 1
+}
+
+func (r regression) String() string {
+	sign := "+"
+	if r.Growth() < 0 {
+		sign = ""
+	}
+	return r.Name + ": P99 " + formatMs(r.Baseline) + " -> " + formatMs(r.Current) + " (" + sign + formatPercent(r.Growth()) + ")"
+}
+
+// Compare the latency at 99% of the endpoints found in the baseline run,
+// matched by name
+func compareBaseline(endpoints []endpointDetails, baseline []endpointDetails) []regression {
+	var comparisons []regression
+	for i := range endpoints {
+		previous := findEndpoint(baseline, endpointName(endpoints[i]))
+		if previous == nil || previous.Metrics.Latencies.P99 <= 0 {
+			os.Stderr.Write([]byte("No baseline latency found for " + endpointName(endpoints[i]) + "\n"))
+			continue
+		}
+		comparisons = append(comparisons, regression{
+			Name:     endpointName(endpoints[i]),
+			Baseline: durationToMs(previous.Metrics.Latencies.P99),
+			Current:  durationToMs(endpoints[i].Metrics.Latencies.P99),
+		})
+	}
+	return comparisons
+}
+
+// Print the comparison of every endpoint and return the names of those whose
+// latency grew by more than the given ratio, if any
+func checkRegressions(comparisons []regression, maxGrowth float64) []string {
+	var regressed []string
+	for _, comparison := range comparisons {
+		line := comparison.String()
+		if maxGrowth >= 0 && comparison.Growth() > maxGrowth {
+			line = "REGRESSION: " + line + ", above the " + formatPercent(maxGrowth) + " tolerance"
+			regressed = append(regressed, comparison.Name)
+		}
+		os.Stderr.Write([]byte(line + "\n"))
+	}
+	return regressed
+}
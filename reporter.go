@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Pipe the JSON results to the stdin of an external command run through the
+// shell, then write whatever the command printed to stderr
+func runExecReporter(ctx context.Context, endpoints []endpointDetails, command string, format latencyFormat) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var input, output bytes.Buffer
+	if err := writeJSONArray(&input, endpoints, format); err != nil {
+		return err
+	}
+	cmd.Stdin = &input
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	os.Stderr.Write(output.Bytes())
+	return err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+type requestIDInfo struct {
+	Header string `json:"header" yaml:"header"`
+	First  string `json:"first" yaml:"first"`
+	Last   string `json:"last" yaml:"last"`
+	Count  uint64 `json:"count" yaml:"count"`
+}
+
+// Stamp every request with a unique ID in the given header. IDs share a
+// random prefix and end with a sequence number, so the IDs sent to an
+// endpoint can be described by their range
+type requestIDs struct {
+	Header string
+	prefix string
+	count  uint64
+}
+
+func newRequestIDs(header string) *requestIDs {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		fatal(exitReasonError, err)
+	}
+	return &requestIDs{Header: header, prefix: "rtapi-" + hex.EncodeToString(b[:]) + "-"}
+}
+
+func (ids *requestIDs) id(n uint64) string {
+	return ids.prefix + strconv.FormatUint(n, 10)
+}
+
+// Wrap a targeter to add a new ID to every target it returns
+func (ids *requestIDs) Targeter(targeter vegeta.Targeter) vegeta.Targeter {
+	return func(tgt *vegeta.Target) error {
+		if err := targeter(tgt); err != nil {
+			return err
+		}
+		// Static targeters share their header between targets
+		tgt.Header = tgt.Header.Clone()
+		if tgt.Header == nil {
+			tgt.Header = make(http.Header)
+		}
+		tgt.Header.Set(ids.Header, ids.id(atomic.AddUint64(&ids.count, 1)))
+		return nil
+	}
+}
+
+func (ids *requestIDs) Result() *requestIDInfo {
+	count := atomic.LoadUint64(&ids.count)
+	if count == 0 {
+		return nil
+	}
+	return &requestIDInfo{Header: ids.Header, First: ids.id(1), Last: ids.id(count), Count: count}
+}
+
+func printRequestIDs(info *requestIDInfo) {
+	os.Stdout.Write([]byte("Request IDs (" + info.Header + "): " + info.First + " to " + info.Last +
+		" (" + strconv.FormatUint(info.Count, 10) + " sent)\n"))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Results of an endpoint merged across the result files
+type mergedEndpoint struct {
+	Details endpointDetails
+	// Result files the endpoint was found in
+	Files  int
+	Spread latencySpread
+}
+
+// Merge the saved vegeta result files matching a glob, e.g. the shards of a
+// test run on several machines, into one endpoint per attack. The files can
+// be in any of vegeta's encodings (gob, JSON or CSV)
+func parseResultFiles(pattern string) []endpointDetails {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	if len(files) == 0 {
+		fatal(exitReasonInvalidInput, "No result files match "+pattern)
+	}
+	var merged []*mergedEndpoint
+	index := make(map[string]*mergedEndpoint)
+	for _, file := range files {
+		seen := make(map[string]bool)
+		err := decodeResultFile(file, func(result *vegeta.Result) {
+			name := resultEndpointName(result)
+			endpoint, ok := index[name]
+			if !ok {
+				endpoint = &mergedEndpoint{Details: endpointDetails{
+					Name:   name,
+					Target: endpointTarget{Method: result.Method, URL: result.URL},
+				}}
+				index[name] = endpoint
+				merged = append(merged, endpoint)
+			}
+			if !seen[name] {
+				seen[name] = true
+				endpoint.Files++
+			}
+			endpoint.Details.Metrics.Add(result)
+			endpoint.Spread.Add(result)
+		})
+		if err != nil {
+			fatal(exitReasonInvalidInput, file+": "+err.Error())
+		}
+	}
+	if len(merged) == 0 {
+		fatal(exitReasonInvalidInput, "No results found in the files matching "+pattern)
+	}
+
+	endpoints := make([]endpointDetails, len(merged))
+	for i, endpoint := range merged {
+		// An endpoint missing from some shards is still reported, its load
+		// just didn't come from every machine
+		if endpoint.Files < len(files) {
+			log.Print("Endpoint ", endpoint.Details.Name, " was only found in ", endpoint.Files, " of ", len(files), " result files")
+		}
+		endpoint.Details.Metrics.Close()
+		endpoint.Details.LatencyStdDev, endpoint.Details.LatencyCV = endpoint.Spread.Result()
+		endpoint.Details.Query = endpointQuery{
+			Duration:    endpoint.Details.Metrics.Duration.Round(time.Second).String(),
+			RequestRate: int(endpoint.Details.Metrics.Rate + 0.5),
+		}
+		endpoints[i] = endpoint.Details
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return endpoints[i].Name < endpoints[j].Name
+	})
+	os.Stderr.Write([]byte("Aggregated " + strconv.Itoa(len(endpoints)) + " endpoints from " + strconv.Itoa(len(files)) + " result files\n"))
+	return endpoints
+}
+
+func decodeResultFile(file string, add func(*vegeta.Result)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	// An empty shard has no results to detect the encoding from
+	if _, err := reader.Peek(1); err == io.EOF {
+		return nil
+	}
+	decoder := vegeta.DecoderFor(reader)
+	if decoder == nil {
+		return errors.New("not a vegeta result file in the gob, JSON or CSV encoding")
+	}
+	for {
+		var result vegeta.Result
+		if err := decoder.Decode(&result); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		add(&result)
+	}
+}
+
+// Results are matched across files by the name of their attack, which rtapi
+// sets to the endpoint name, or by their request if the attack is unnamed
+func resultEndpointName(result *vegeta.Result) string {
+	if result.Attack != "" {
+		return result.Attack
+	}
+	if result.URL != "" {
+		return result.Method + " " + result.URL
+	}
+	return "unnamed"
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+type connectionReuse struct {
+	// Requests sent over an idle connection and over a newly opened one
+	Reused uint64 `json:"reused" yaml:"reused"`
+	New    uint64 `json:"new" yaml:"new"`
+	// Share of the requests that reused a connection
+	Ratio float64 `json:"ratio" yaml:"ratio"`
+}
+
+// Count the connections requests were sent over, using the GotConn hook of
+// httptrace to tell reused connections from new ones
+type connectionCounter struct {
+	reused uint64
+	new    uint64
+}
+
+func (cc *connectionCounter) gotConn(info httptrace.GotConnInfo) {
+	if info.Reused {
+		atomic.AddUint64(&cc.reused, 1)
+	} else {
+		atomic.AddUint64(&cc.new, 1)
+	}
+}
+
+// Wrap a client so every request it sends is counted. Vegeta's transport
+// options expect an *http.Transport, so the wrapped client must be passed
+// to the attacker after all of them, and the transport they tweaked must be
+// the one wrapped
+func (cc *connectionCounter) Client(client *http.Client) *http.Client {
+	trace := &httptrace.ClientTrace{GotConn: cc.gotConn}
+	traced := *client
+	traced.Transport = tracingTransport{RoundTripper: client.Transport, trace: trace}
+	return &traced
+}
+
+func (cc *connectionCounter) Result() *connectionReuse {
+	reused := atomic.LoadUint64(&cc.reused)
+	opened := atomic.LoadUint64(&cc.new)
+	if reused+opened == 0 {
+		return nil
+	}
+	return &connectionReuse{Reused: reused, New: opened, Ratio: float64(reused) / float64(reused+opened)}
+}
+
+type tracingTransport struct {
+	http.RoundTripper
+	trace *httptrace.ClientTrace
+}
+
+func (t tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.RoundTripper.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), t.trace)))
+}
+
+func printConnectionReuse(reuse *connectionReuse) {
+	os.Stdout.Write([]byte("Connection reuse: " + formatPercent(reuse.Ratio) + " (" +
+		strconv.FormatUint(reuse.Reused, 10) + " reused, " + strconv.FormatUint(reuse.New, 10) + " new connections)\n"))
+}
@@ -2,12 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"image/color"
+	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,9 +33,78 @@ import (
 )
 
 type endpointDetails struct {
-	Target  endpointTarget `json:"target" yaml:"target"`
-	Query   endpointQuery  `json:"query_parameters" yaml:"query_parameters"`
-	Metrics vegeta.Metrics `json:"metrics" yaml:"metrics"`
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Name given to the vegeta attack and stamped on its results, defaulting to the endpoint name
+	AttackName string `json:"attack_name,omitempty" yaml:"attack_name,omitempty"`
+	// Team owning the endpoint and how to reach it, to route its failures
+	Owner   string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Contact string `json:"contact,omitempty" yaml:"contact,omitempty"`
+	// Relative importance of the endpoint's traffic in aggregate results
+	Weight float64        `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Target endpointTarget `json:"target" yaml:"target"`
+	// Exact status code every response must have, reported as a failure otherwise
+	ExpectStatus int `json:"expect_status,omitempty" yaml:"expect_status,omitempty"`
+	// Media type every 2xx response must have, e.g. "application/json",
+	// the response being reclassified as a failure otherwise
+	ExpectContentType string `json:"expect_content_type,omitempty" yaml:"expect_content_type,omitempty"`
+	// Absolute number of failed requests from which the endpoint fails, regardless of its error ratio
+	MaxErrors *int `json:"max_errors,omitempty" yaml:"max_errors,omitempty"`
+	// Longest latency any single request may take, e.g. "1s", regardless of the percentiles
+	MaxLatency string         `json:"max_latency,omitempty" yaml:"max_latency,omitempty"`
+	Query      endpointQuery  `json:"query_parameters" yaml:"query_parameters"`
+	Metrics    vegeta.Metrics `json:"metrics" yaml:"metrics"`
+	// Unit of the latencies in a JSON output written with --latency-unit or
+	// --latency-precision, vegeta's nanosecond integers if unset
+	LatencyUnit string `json:"latency_unit,omitempty" yaml:"latency_unit,omitempty"`
+	// Standard deviation of the latency and its coefficient of variation (standard deviation / mean)
+	LatencyStdDev time.Duration `json:"latency_stddev,omitempty" yaml:"latency_stddev,omitempty"`
+	LatencyCV     float64       `json:"latency_cv,omitempty" yaml:"latency_cv,omitempty"`
+	// Workers chosen by probing, only with --max-workers-auto
+	WorkerTuning *workerTuning `json:"worker_tuning,omitempty" yaml:"worker_tuning,omitempty"`
+	// Maximum rate measured by the probe, only if a rate percentage was requested
+	DiscoveredMaxRate float64 `json:"discovered_max_rate,omitempty" yaml:"discovered_max_rate,omitempty"`
+	// Details of the rate limiting, only if a significant share of requests was rate limited
+	RateLimited *rateLimitInfo `json:"rate_limited,omitempty" yaml:"rate_limited,omitempty"`
+	// Achieved rate at each step of a worker ramp
+	RampStages []rampStage `json:"ramp_stages,omitempty" yaml:"ramp_stages,omitempty"`
+	// Whether --fail-fast stopped the attack when the running P99 exceeded the threshold
+	FailedFast bool `json:"failed_fast,omitempty" yaml:"failed_fast,omitempty"`
+	// Sample of the response headers, only if requested
+	ResponseHeaders http.Header `json:"response_headers,omitempty" yaml:"response_headers,omitempty"`
+	// First failed requests with their responses, only if requested
+	FailureSamples []failureSample `json:"failure_samples,omitempty" yaml:"failure_samples,omitempty"`
+	// Number of responses without the expected status code, if one was set
+	StatusMismatches uint64 `json:"status_mismatches,omitempty" yaml:"status_mismatches,omitempty"`
+	// Number of 2xx responses reclassified as failures for their content type, if one was expected
+	ContentTypeMismatches uint64 `json:"content_type_mismatches,omitempty" yaml:"content_type_mismatches,omitempty"`
+	// Number of failed requests, only if a maximum was set
+	Errors uint64 `json:"errors,omitempty" yaml:"errors,omitempty"`
+	// Requested and achieved rates, only if the achieved rate fell short of --min-rate-percent
+	RateShortfall *rateShortfall `json:"rate_shortfall,omitempty" yaml:"rate_shortfall,omitempty"`
+	// Requests, errors and mean latency per second, only with --error-band or --rate-timeline
+	Timeline []timelineBucket `json:"timeline,omitempty" yaml:"timeline,omitempty"`
+	// Confidence intervals of the tail percentiles, only with --percentile-ci
+	PercentileIntervals []percentileInterval `json:"percentile_intervals,omitempty" yaml:"percentile_intervals,omitempty"`
+	// Latency of the responses of each status class, only with --latency-by-status
+	StatusClasses []statusClassLatency `json:"status_classes,omitempty" yaml:"status_classes,omitempty"`
+	// Number of requests per latency bucket, only with --histogram
+	Histogram *vegeta.Histogram `json:"histogram,omitempty" yaml:"histogram,omitempty"`
+	// Requests which failed on a redirect loop or too many redirects, if any
+	Redirects *redirectInfo `json:"redirects,omitempty" yaml:"redirects,omitempty"`
+	// Requests sent over reused and new connections
+	ConnectionReuse *connectionReuse `json:"connection_reuse,omitempty" yaml:"connection_reuse,omitempty"`
+	// Number of responses received over each protocol, e.g. "HTTP/2.0"
+	Protocols map[string]uint64 `json:"protocols,omitempty" yaml:"protocols,omitempty"`
+	// Requested pipeline depth and the depth achieved, only if pipelining
+	Pipelining *pipeliningInfo `json:"pipelining,omitempty" yaml:"pipelining,omitempty"`
+	// Range of the IDs stamped on the requests, only with --request-id-header
+	RequestIDs *requestIDInfo `json:"request_ids,omitempty" yaml:"request_ids,omitempty"`
+	// Detected warm-up excluded from the metrics, only with --auto-warmup
+	Warmup *warmupInfo `json:"warmup,omitempty" yaml:"warmup,omitempty"`
+	// Share of requests under each SLA latency, only if SLA targets were given
+	SLA []slaResult `json:"sla,omitempty" yaml:"sla,omitempty"`
+	// Machine and configuration the results were measured with
+	Environment *runEnvironment `json:"environment,omitempty" yaml:"environment,omitempty"`
 }
 
 type endpointTarget struct {
@@ -36,20 +112,68 @@ type endpointTarget struct {
 	URL    string      `json:"url" yaml:"url"`
 	Body   string      `json:"body" yaml:"body"`
 	Header http.Header `json:"header" yaml:"header"`
+	// Headers applied in order after Header, allowing repeated keys
+	HeaderList []headerField `json:"header_list,omitempty" yaml:"header_list,omitempty"`
+	// Path to a text/template file rendered as the body, overriding Body
+	BodyTemplate string                 `json:"body_template,omitempty" yaml:"body_template,omitempty"`
+	TemplateData map[string]interface{} `json:"template_data,omitempty" yaml:"template_data,omitempty"`
+	// Size of a synthetic body, e.g. "1MB", overriding Body and BodyTemplate.
+	// It's filled with zeros, or random bytes if BodyFill is "random"
+	BodySize string `json:"body_size,omitempty" yaml:"body_size,omitempty"`
+	BodyFill string `json:"body_fill,omitempty" yaml:"body_fill,omitempty"`
+	// PEM client certificate and key presented for mutual TLS. An encrypted
+	// key is decrypted with the passphrase held by the environment variable
+	// named by ClientKeyPassphraseEnv, RTAPI_CLIENT_KEY_PASSPHRASE by default
+	ClientCert             string `json:"client_cert,omitempty" yaml:"client_cert,omitempty"`
+	ClientKey              string `json:"client_key,omitempty" yaml:"client_key,omitempty"`
+	ClientKeyPassphraseEnv string `json:"client_key_passphrase_env,omitempty" yaml:"client_key_passphrase_env,omitempty"`
 }
 
 type endpointQuery struct {
-	Threads     uint64 `json:"threads" yaml:"threads"`
-	MaxThreads  uint64 `json:"max_threads" yaml:"max_threads"`
-	Connections int    `json:"connections" yaml:"connections"`
-	Duration    string `json:"duration" yaml:"duration"`
-	RequestRate int    `json:"request_rate" yaml:"request_rate"`
+	Threads     uint64        `json:"threads" yaml:"threads"`
+	MaxThreads  uint64        `json:"max_threads" yaml:"max_threads"`
+	Connections int           `json:"connections" yaml:"connections"`
+	Duration    string        `json:"duration" yaml:"duration"`
+	RequestRate int           `json:"request_rate" yaml:"request_rate"`
+	Pacer       endpointPacer `json:"pacer" yaml:"pacer"`
+	// Open a fresh connection for every request to measure cold-start latency
+	DisableKeepAlive bool `json:"disable_keep_alive" yaml:"disable_keep_alive"`
+	// Send the body with chunked transfer encoding instead of a Content-Length
+	Chunked bool `json:"chunked" yaml:"chunked"`
+	// Wait for a "100 Continue" response before sending the body
+	Expect100Continue bool `json:"expect_100_continue" yaml:"expect_100_continue"`
+	// Ramp the number of concurrent workers from Threads to MaxThreads in
+	// RampSteps steps instead of sending requests at RequestRate
+	WorkerRamp bool `json:"worker_ramp" yaml:"worker_ramp"`
+	RampSteps  int  `json:"ramp_steps" yaml:"ramp_steps"`
+	// DNS servers to resolve the target with instead of the system resolver,
+	// e.g. "10.0.0.53" or "10.0.0.53:5353"
+	DNSServers []string `json:"dns_servers,omitempty" yaml:"dns_servers,omitempty"`
+	// Maximum number of redirects to follow, 10 if unset or -1 to not follow
+	// redirects and measure the redirect responses themselves
+	MaxRedirects int `json:"max_redirects,omitempty" yaml:"max_redirects,omitempty"`
+	// Number of requests sent on an HTTP/1.1 connection before reading their
+	// responses, with a pipelining transport replacing Go's, which never
+	// pipelines. 0 or 1 doesn't pipeline
+	Pipeline int `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
+	// Speak HTTP/2 over cleartext connections from the first request (h2c
+	// with prior knowledge) instead of HTTP/1.1
+	H2C bool `json:"h2c,omitempty" yaml:"h2c,omitempty"`
 }
 
+// NGINX's definition of a real-time API, in milliseconds at the 99th percentile
+const defaultThreshold = 30
+
+// Profile name of a Splunk settings file holding a single settings object
+const defaultSplunkProfile = "default"
+
 type splunkSettings struct {
 	Url     string `json:"url" yaml:"url"`
 	Authkey string `json:"authkey" yaml:"authkey"`
 	Source  string `json:"source" yaml:"source"`
+	// Which time to stamp events with: "start" or "end" of the endpoint's
+	// attack, or "send" for the time the event is sent to Splunk
+	TimeSource string `json:"time_source" yaml:"time_source"`
 }
 
 type splunkEvent struct {
@@ -59,22 +183,222 @@ type splunkEvent struct {
 	Event  endpointDetails `json:"event" yaml:"event"`
 }
 
+type queryOptions struct {
+	CaptureHeaders bool
+	FailureSamples int
+	// Share of rate limited responses from which an endpoint is reported as rate limited
+	RateLimitThreshold float64
+	// Pause the attack whenever the endpoint rate limits it
+	RateLimitBackoff bool
+	// Stop the attack as soon as its running P99 exceeds this threshold in
+	// milliseconds, or never if zero
+	FailFastThreshold float64
+	SLA               []slaTarget
+	// Only measure the responses received once latency has stabilized
+	AutoWarmup bool
+	// Header stamped with a unique ID on every request, if any
+	RequestIDHeader string
+	// Latency buckets requests are counted in, if any
+	HistogramBuckets vegeta.Buckets
+	// Record the requests, errors and latency of every second of the attack
+	Timeline bool
+	// Break the latency down by status class
+	StatusClasses bool
+}
+
+type graphOptions struct {
+	Threshold float64
+	TopN      int
+	TopOrder  string
+	// Fade the lines of endpoints with fewer requests
+	WeightByVolume bool
+	// Replace the graph with a table of the latency of each endpoint
+	NoGraph bool
+	// Add a graph of the latency over time, shading the seconds in which the
+	// share of errors exceeded this ratio, or no such graph if zero
+	ErrorBand float64
+	// Events marked on the graph of the latency over time
+	Events []graphEvent
+	// Add a graph of the achieved rate over time
+	RateTimeline bool
+	// Append the effective configuration of the endpoints
+	ConfigAppendix bool
+	// Percentiles of the latency table
+	Percentiles []float64
+	// Minimum of the latency axis in milliseconds, to zoom into low latencies
+	YMin float64
+}
+
 func main() {
 	flags := []cli.Flag{
 		&cli.StringFlag{
 			Name:    "file",
 			Aliases: []string{"f"},
-			Usage:   "select a JSON or YAML file to load",
+			Usage:   "select a JSON or YAML file to load, or an http(s) URL serving one",
+		},
+		&cli.IntFlag{
+			Name:  "config-retries",
+			Value: 3,
+			Usage: "attempts at fetching a --file URL before falling back on its cached copy",
+		},
+		&cli.DurationFlag{
+			Name:  "config-cache-ttl",
+			Value: time.Hour,
+			Usage: "how old the cached copy of a --file URL may be to be used when fetching it fails, or 0 to never use it",
 		},
 		&cli.StringFlag{
 			Name:    "data",
 			Aliases: []string{"d"},
 			Usage:   "input API parameters directly as a JSON string",
 		},
+		&cli.BoolFlag{
+			Name:  "lenient",
+			Usage: "skip and report malformed endpoints of the file or data instead of failing, running the valid ones",
+		},
+		&cli.StringFlag{
+			Name:  "har",
+			Usage: "replay the requests captured in a HAR file",
+		},
+		&cli.StringFlag{
+			Name:  "har-url",
+			Usage: "only replay HAR requests whose URL matches the specified regular expression",
+		},
+		&cli.StringSliceFlag{
+			Name:  "har-content-type",
+			Usage: "only replay HAR requests whose response has one of the specified content types",
+		},
+		&cli.StringFlag{
+			Name:  "url-list",
+			Usage: "query each URL listed, one per line, in a plain text file",
+		},
+		&cli.StringFlag{
+			Name:  "aggregate-results",
+			Usage: "report on the vegeta result files matching a glob, e.g. \"shards/*.bin\", merged per attack, instead of querying any endpoint",
+		},
+		&cli.IntFlag{
+			Name:  "rate",
+			Value: 500,
+			Usage: "request rate per second used for endpoints read from a HAR file or URL list, and by --self-test",
+		},
+		&cli.StringFlag{
+			Name:  "duration",
+			Value: "10s",
+			Usage: "duration used for endpoints read from a HAR file or URL list, and by --self-test",
+		},
+		&cli.StringFlag{
+			Name:  "method",
+			Value: "GET",
+			Usage: "method used for endpoints read from a URL list",
+		},
+		&cli.StringFlag{
+			Name:  "method-override",
+			Usage: "query every endpoint with the specified HTTP method instead of its own for this run, e.g. HEAD",
+		},
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "override the duration, rate and workers of every endpoint with a named profile: smoke, soak, spike or one from --profiles",
+		},
+		&cli.StringFlag{
+			Name:  "profiles",
+			Usage: "load additional named profiles from a JSON or YAML file, taking precedence over the built-in ones",
+		},
+		&cli.StringFlag{
+			Name:  "conn-sweep",
+			Usage: "query each endpoint once per connection count in a comma separated list, e.g. \"1,5,10,50,100\"",
+		},
+		&cli.StringFlag{
+			Name:  "conn-sweep-graph",
+			Usage: "output a PNG graph of the connection sweep (use - for stdout)",
+		},
+		&cli.Float64Flag{
+			Name:  "rate-percent",
+			Usage: "query each endpoint at the specified percentage of its maximum rate, discovered by an uncapped probe",
+		},
+		&cli.StringFlag{
+			Name:  "probe-duration",
+			Value: "5s",
+			Usage: "duration of the probes used by --rate-percent and --max-workers-auto",
+		},
+		&cli.BoolFlag{
+			Name:  "max-workers-auto",
+			Usage: "probe each endpoint with twice as many workers until it reaches its requested rate, then test it with that many",
+		},
+		&cli.Uint64Flag{
+			Name:  "max-workers-ceiling",
+			Value: 1024,
+			Usage: "most workers --max-workers-auto may choose",
+		},
+		&cli.StringFlag{
+			Name:  "trend",
+			Usage: "compare the latency at 99% of each endpoint across runs saved with --json, e.g. \"run1.json,run2.json\"",
+		},
+		&cli.StringFlag{
+			Name:  "trend-graph",
+			Usage: "output a PNG graph of the --trend comparison (use - for stdout)",
+		},
 		&cli.StringFlag{
 			Name:    "output",
 			Aliases: []string{"o"},
-			Usage:   "output query results in easy to grasp PDF report",
+			Usage:   "output query results in easy to grasp PDF report (use - for stdout)",
+		},
+		&cli.BoolFlag{
+			Name:  "estimate",
+			Usage: "report the number of pages and graphs and the size of the PDF report instead of writing it",
+		},
+		&cli.Float64Flag{
+			Name:  "threshold",
+			Value: defaultThreshold,
+			Usage: "latency in milliseconds an API must stay below to be considered real time",
+		},
+		&cli.Float64Flag{
+			Name:  "aggregate-threshold",
+			Usage: "fail if the latency at 99% of the traffic of all endpoints exceeds the specified milliseconds",
+		},
+		&cli.IntFlag{
+			Name:  "graph-top-n",
+			Usage: "only plot the N endpoints with the worst (or best) latency at 99%",
+		},
+		&cli.BoolFlag{
+			Name:  "no-graph",
+			Usage: "replace the graph in the PDF report with a table of the latency of each endpoint",
+		},
+		&cli.Float64Flag{
+			Name:  "y-min",
+			Usage: "start the latency axis of the graph at the specified milliseconds instead of 0, to zoom into low latencies",
+		},
+		&cli.StringFlag{
+			Name:  "percentiles",
+			Usage: "report the latency at the specified percentiles in the text report and latency table, e.g. \"50,75,90,95,99,99.9\"",
+		},
+		&cli.BoolFlag{
+			Name:  "config-appendix",
+			Usage: "append the effective configuration of the endpoints to the PDF report",
+		},
+		&cli.Float64Flag{
+			Name:  "error-band",
+			Usage: "add a graph of latency over time to the PDF report, shaded where the share of errors exceeds the specified percentage",
+		},
+		&cli.StringFlag{
+			Name:  "events",
+			Usage: "mark events on the --error-band and --rate-timeline graphs at their time since the start of each endpoint's test, e.g. \"30s:deploy,60s:cache-flush\"",
+		},
+		&cli.BoolFlag{
+			Name:  "rate-timeline",
+			Usage: "report the achieved request rate of each endpoint per second against the requested rate, in the text report and as a graph in the PDF report",
+		},
+		&cli.StringFlag{
+			Name:  "sort",
+			Value: "config",
+			Usage: "order of the endpoints in every report: \"config\", \"p99\" (worst first), \"name\" or \"success\" (lowest first)",
+		},
+		&cli.StringFlag{
+			Name:  "graph-top-order",
+			Value: "worst",
+			Usage: "whether --graph-top-n selects the \"worst\" or \"best\" endpoints",
+		},
+		&cli.BoolFlag{
+			Name:  "graph-weight-by-volume",
+			Usage: "fade the graph lines of endpoints with fewer requests, noting the request count of each in the legend",
 		},
 		&cli.BoolFlag{
 			Name:    "print",
@@ -86,11 +410,149 @@ func main() {
 			Aliases: []string{"j"},
 			Usage:   "output technical query results as json to terminal",
 		},
+		&cli.StringFlag{
+			Name:  "latency-unit",
+			Value: "ns",
+			Usage: "unit of the latencies in the json outputs: ns, us, ms or s",
+		},
+		&cli.IntFlag{
+			Name:  "latency-precision",
+			Usage: "number of decimals of the latencies in the json outputs, as many as needed if not set",
+		},
+		&cli.BoolFlag{
+			Name:  "explain",
+			Usage: "describe in plain English why each endpoint passed or failed",
+		},
+		&cli.BoolFlag{
+			Name:  "tui",
+			Usage: "browse the results in an interactive terminal UI after the run",
+		},
+		&cli.StringFlag{
+			Name:  "badge",
+			Usage: "output an SVG badge stating whether all endpoints passed, with the worst latency at 99% (use - for stdout)",
+		},
+		&cli.StringFlag{
+			Name:  "per-endpoint-dir",
+			Usage: "write the json results of each endpoint to a separate file in the specified directory",
+		},
 		&cli.StringFlag{
 			Name:    "splunk",
 			Aliases: []string{"s"},
 			Usage:   "send json output to splunk with specified authorisation key",
 		},
+		&cli.StringFlag{
+			Name:  "splunk-profile",
+			Usage: "select the named profile of the --splunk settings file",
+		},
+		&cli.StringFlag{
+			Name:  "grafana",
+			Usage: "annotate Grafana dashboards with the run using the settings in the specified JSON or YAML file",
+		},
+		&cli.StringFlag{
+			Name:  "elastic",
+			Usage: "index the results in Elasticsearch using the settings in the specified JSON or YAML file",
+		},
+		&cli.StringFlag{
+			Name:  "exec-reporter",
+			Usage: "pipe the json results to the stdin of the specified shell command after the run",
+		},
+		&cli.BoolFlag{
+			Name:  "headers",
+			Usage: "capture a sample of the response headers of each endpoint",
+		},
+		&cli.StringFlag{
+			Name:  "baseline",
+			Usage: "compare the latency at 99% of each endpoint against a previous json output file",
+		},
+		&cli.Float64Flag{
+			Name:  "max-regression",
+			Usage: "fail if the latency at 99% of any endpoint grew by more than the specified percentage over the --baseline",
+		},
+		&cli.StringFlag{
+			Name:  "headers-baseline",
+			Usage: "compare captured response headers against a previous json output file",
+		},
+		&cli.IntFlag{
+			Name:  "failure-samples",
+			Usage: "include the first N failed requests and their responses in the text and json reports",
+		},
+		&cli.Float64Flag{
+			Name:  "rate-limit-threshold",
+			Value: 1,
+			Usage: "percentage of 429 responses from which an endpoint is reported as rate limiting the test",
+		},
+		&cli.BoolFlag{
+			Name:  "rate-limit-backoff",
+			Usage: "pause the test of an endpoint for as long as its 429 responses ask to (Retry-After)",
+		},
+		&cli.IntFlag{
+			Name:  "export-concurrency",
+			Value: 4,
+			Usage: "maximum number of events sent to Splunk at once",
+		},
+		&cli.DurationFlag{
+			Name:  "export-timeout",
+			Usage: "give up on exporting the results to Splunk, Grafana and Elasticsearch after the specified duration, including retries",
+		},
+		&cli.StringFlag{
+			Name:  "request-id-header",
+			Usage: "stamp every request with a unique ID in the specified header, e.g. X-Request-ID",
+		},
+		&cli.BoolFlag{
+			Name:  "auto-warmup",
+			Usage: "exclude the requests sent until latency stabilizes from the metrics of each endpoint",
+		},
+		&cli.StringFlag{
+			Name:  "histogram",
+			Usage: "count the requests of each endpoint in the specified latency buckets, e.g. \"0,10ms,30ms,50ms,100ms\"",
+		},
+		&cli.BoolFlag{
+			Name:  "percentile-ci",
+			Usage: "estimate a 95% confidence interval of the latency of each endpoint at 95% and 99%, flagging those backed by too few requests",
+		},
+		&cli.BoolFlag{
+			Name:  "latency-by-status",
+			Usage: "break the latency of each endpoint down by status class (2xx, 3xx, 4xx and 5xx)",
+		},
+		&cli.StringFlag{
+			Name:  "sla",
+			Usage: "report the share of requests under each latency against a target percentage, e.g. \"95:50ms,99:200ms\"",
+		},
+		&cli.Float64Flag{
+			Name:  "max-host-rate",
+			Usage: "refuse to run if any host would be queried above the specified rate per second",
+		},
+		&cli.Float64Flag{
+			Name:  "min-rate-percent",
+			Usage: "warn about endpoints queried at less than the specified percentage of their requested rate, e.g. 90",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-on-rate-shortfall",
+			Usage: "fail instead of warning about endpoints queried below --min-rate-percent",
+		},
+		&cli.BoolFlag{
+			Name:  "fail-fast",
+			Usage: "abort the run as soon as an endpoint's P99 exceeds the threshold, skipping the remaining endpoints and outputs",
+		},
+		&cli.IntFlag{
+			Name:  "timeout-exit-code",
+			Value: exitReliabilityBreach,
+			Usage: "exit code when no request to an endpoint got a response, all of them timing out or failing to connect",
+		},
+		&cli.BoolFlag{
+			Name:  "self-test",
+			Usage: "validate the measurements against an in-process server with a known latency instead of querying any endpoint",
+		},
+		&cli.DurationFlag{
+			Name:  "self-test-latency",
+			Value: 20 * time.Millisecond,
+			Usage: "latency the --self-test server answers after",
+		},
+		&cli.Float64Flag{
+			Name:  "self-test-tolerance",
+			Value: 5,
+			Usage: "milliseconds of overhead at 99% above the --self-test latency from which the self-test fails",
+		},
 		&cli.BoolFlag{
 			Name:    "quiet",
 			Aliases: []string{"q"},
@@ -100,97 +562,563 @@ func main() {
 
 	app := &cli.App{
 		Name:    "Real time API latency analyzer",
-		Version: "v0.2.0",
+		Version: version,
 		Usage:   "Create a PDF report and HDR histogram of Your APIs",
 		Flags:   flags,
+		// Every non-zero exit ends with a machine readable reason on stderr
+		ExitErrHandler: handleExitError,
 		Action: func(c *cli.Context) error {
+			// Validate the measurements instead of querying any endpoint
+			if c.Bool("self-test") {
+				return runSelfTest(c.Duration("self-test-latency"), c.Int("rate"), c.String("duration"), c.Float64("self-test-tolerance"))
+			}
+
+			// Compare saved runs instead of querying any endpoint
+			if c.IsSet("trend") {
+				runs := loadTrendRuns(strings.Split(c.String("trend"), ","))
+				if c.String("trend-graph") != "-" {
+					printTrend(runs)
+				}
+				if c.IsSet("trend-graph") {
+					file := createOutputFile(c.String("trend-graph"))
+					createTrendGraph(runs, c.Float64("threshold")).WriteTo(file)
+					file.Close()
+				}
+				return nil
+			}
+
 			// Check if there's any input data
 			var endpointList []endpointDetails
 			var splunkSettings splunkSettings
-			if !c.IsSet("file") && !c.IsSet("data") {
-				log.Fatal("No data found")
-			} else if c.IsSet("file") && c.IsSet("data") {
-				log.Fatal("Please only use either file or data as your input source")
-			} else if !c.IsSet("output") && !c.Bool("print") && !c.Bool("json") && c.String("splunk") == "" {
-				log.Fatal("You did not specify any type of output")
+			var grafanaSettings grafanaSettings
+			var elasticSettings elasticSettings
+			var skipped []skippedEntry
+			inputs := countSet(c, "file", "data", "har", "url-list", "aggregate-results")
+			if inputs == 0 {
+				fatal(exitReasonInvalidInput, "No data found")
+			} else if inputs > 1 {
+				fatal(exitReasonInvalidInput, "Please only use one of file, data, har, url-list or aggregate-results as your input source")
+			} else if c.IsSet("aggregate-results") && countSet(c, "conn-sweep", "rate-percent", "max-workers-auto", "profile", "method-override", "fail-fast") > 0 {
+				fatal(exitReasonInvalidInput, "Saved results can't be queried again with --conn-sweep, --rate-percent, --max-workers-auto, --profile, --method-override or --fail-fast")
+			} else if c.IsSet("aggregate-results") && countSet(c, "sla", "histogram", "auto-warmup", "latency-by-status", "headers", "error-band", "rate-timeline", "failure-samples") > 0 {
+				// Only the metrics of saved results are merged
+				fatal(exitReasonInvalidInput, "Saved results only have metrics to report on, not --sla, --histogram, --auto-warmup, --latency-by-status, --headers, --error-band, --rate-timeline or --failure-samples")
+			} else if !c.IsSet("output") && !c.Bool("estimate") && !c.Bool("print") && !c.Bool("json") && !c.Bool("explain") && !c.Bool("tui") && !c.IsSet("per-endpoint-dir") && !c.IsSet("badge") && !c.IsSet("conn-sweep") && c.String("splunk") == "" && !c.IsSet("grafana") && !c.IsSet("elastic") && !c.IsSet("exec-reporter") {
+				fatal(exitReasonInvalidInput, "You did not specify any type of output")
+			} else if c.String("graph-top-order") != "worst" && c.String("graph-top-order") != "best" {
+				fatal(exitReasonInvalidInput, "The graph top order must be either worst or best")
+			} else if _, ok := endpointOrders[c.String("sort")]; !ok && c.String("sort") != "config" {
+				fatal(exitReasonInvalidInput, "The sort order must be one of config, p99, name or success")
+			} else if c.IsSet("rate-percent") && (c.Float64("rate-percent") <= 0 || c.Float64("rate-percent") > 100) {
+				fatal(exitReasonInvalidInput, "The rate percentage must be greater than 0 and at most 100")
+			} else if c.IsSet("min-rate-percent") && (c.Float64("min-rate-percent") <= 0 || c.Float64("min-rate-percent") > 100) {
+				fatal(exitReasonInvalidInput, "The minimum rate percentage must be greater than 0 and at most 100")
+			} else if c.Bool("fail-on-rate-shortfall") && !c.IsSet("min-rate-percent") {
+				fatal(exitReasonInvalidInput, "Failing on a rate shortfall needs --min-rate-percent")
+			} else if c.IsSet("events") && !c.IsSet("error-band") && !c.Bool("rate-timeline") {
+				fatal(exitReasonInvalidInput, "Events are marked on the --error-band and --rate-timeline graphs, one of which needs to be enabled")
+			} else if c.IsSet("max-regression") && !c.IsSet("baseline") {
+				fatal(exitReasonInvalidInput, "Failing on a regression needs a --baseline")
+			} else if c.IsSet("max-regression") && c.Float64("max-regression") < 0 {
+				fatal(exitReasonInvalidInput, "The maximum regression percentage must not be negative")
+			} else if c.Float64("y-min") < 0 {
+				fatal(exitReasonInvalidInput, "The minimum of the latency axis must not be negative")
+			} else if c.IsSet("error-band") && (c.Float64("error-band") <= 0 || c.Float64("error-band") > 100) {
+				fatal(exitReasonInvalidInput, "The error band percentage must be greater than 0 and at most 100")
+			} else if _, ok := latencyUnits[c.String("latency-unit")]; !ok {
+				fatal(exitReasonInvalidInput, "The latency unit must be one of ns, us, ms or s")
+			} else if c.Int("timeout-exit-code") < 1 || c.Int("timeout-exit-code") > 255 {
+				fatal(exitReasonInvalidInput, "The timeout exit code must be between 1 and 255")
+			} else if c.Bool("tui") && !isInteractiveTerminal() {
+				fatal(exitReasonInvalidInput, "The TUI needs an interactive terminal")
+			} else if stdout := countStdout(c, "output", "badge"); stdout > 1 || stdout == 1 && (c.Bool("print") || c.Bool("json") || c.Bool("explain") || c.Bool("tui")) {
+				fatal(exitReasonInvalidInput, "Only one output can be written to stdout at a time")
 			} else if c.IsSet("file") {
-				if filepath.Ext(c.String("file")) == ".json" {
-					endpointList = parseEndpointsJSON(c.String("file"))
-				} else if filepath.Ext(c.String("file")) == ".yml" || filepath.Ext(c.String("file")) == ".yaml" {
-					endpointList = parseEndpointsYAML(c.String("file"))
+				file := c.String("file")
+				if isConfigURL(file) {
+					file = fetchConfig(file, c.Int("config-retries"), c.Duration("config-cache-ttl"))
+				}
+				isYAML := filepath.Ext(file) == ".yml" || filepath.Ext(file) == ".yaml"
+				if c.Bool("lenient") && (isYAML || filepath.Ext(file) == ".json") {
+					endpointList, skipped = parseEndpointsFileLenient(file, isYAML)
+				} else if filepath.Ext(file) == ".json" {
+					endpointList = parseEndpointsJSON(file)
+				} else if isYAML {
+					endpointList = parseEndpointsYAML(file)
 				}
 			} else if c.IsSet("data") {
-				endpointList = parseJSONString(c.String("data"))
+				if c.Bool("lenient") {
+					endpointList, skipped = parseEndpointsJSONLenient([]byte(c.String("data")))
+				} else {
+					endpointList = parseJSONString(c.String("data"))
+				}
+			} else if c.IsSet("har") {
+				query := defaultQuery()
+				query.RequestRate = c.Int("rate")
+				query.Duration = c.String("duration")
+				endpointList = parseEndpointsHAR(c.String("har"), harFilter{
+					URL:          c.String("har-url"),
+					ContentTypes: c.StringSlice("har-content-type"),
+				}, query)
+			} else if c.IsSet("url-list") {
+				query := defaultQuery()
+				query.RequestRate = c.Int("rate")
+				query.Duration = c.String("duration")
+				endpointList = parseURLList(c.String("url-list"), c.String("method"), query)
+			} else if c.IsSet("aggregate-results") {
+				endpointList = parseResultFiles(c.String("aggregate-results"))
+			}
+			exitCodes[exitReasonUnreachable] = c.Int("timeout-exit-code")
+			if len(skipped) > 0 && len(endpointList) == 0 {
+				fatal(exitReasonInvalidInput, "All "+strconv.Itoa(len(skipped))+" endpoints are malformed")
+			}
+			if c.IsSet("profile") {
+				var userProfiles map[string]queryProfile
+				if c.IsSet("profiles") {
+					parseSettingsFile(c.String("profiles"), &userProfiles)
+				}
+				profile := selectProfile(c.String("profile"), userProfiles)
+				for i := range endpointList {
+					applyProfile(&endpointList[i].Query, profile)
+				}
+			}
+			if c.IsSet("method-override") {
+				overrideMethod(endpointList, c.String("method-override"))
+			}
+			if c.IsSet("max-host-rate") {
+				if hosts := hostsOverRate(endpointList, c.Float64("max-host-rate")); len(hosts) > 0 {
+					fatal(exitReasonInvalidInput, "Some hosts would be queried above the maximum rate of "+
+						formatRate(c.Float64("max-host-rate"))+": "+strings.Join(hosts, ", "))
+				}
 			}
 
 			if c.IsSet("splunk") {
 				//log.Printf(c.String("splunk"))
-				if filepath.Ext(c.String("splunk")) == ".json" {
-					splunkSettings = parseSplunkSettingsJSON(c.String("splunk"))
-				} else if filepath.Ext(c.String("splunk")) == ".yml" || filepath.Ext(c.String("splunk")) == ".yaml" {
-					splunkSettings = parseSplunkSettingsYAML(c.String("splunk"))
-				}
+				splunkSettings = selectSplunkProfile(parseSplunkProfiles(c.String("splunk")), c.String("splunk-profile"))
+			}
+
+			if c.IsSet("grafana") {
+				parseSettingsFile(c.String("grafana"), &grafanaSettings)
+			}
+
+			if c.IsSet("elastic") {
+				parseSettingsFile(c.String("elastic"), &elasticSettings)
+			}
+
+			var events []graphEvent
+			if c.IsSet("events") {
+				events = parseEvents(c.String("events"))
 			}
 
+			var percentiles []float64
+			if c.IsSet("percentiles") {
+				percentiles = parsePercentiles(c.String("percentiles"))
+			}
+
+			var sweep []int
+			if c.IsSet("conn-sweep") {
+				sweep = parseConnSweep(c.String("conn-sweep"))
+			}
+
+			// Saved results were measured already
+			aggregated := c.IsSet("aggregate-results")
+
 			// Show progress bar
 			var sum float64
 			for i := range endpointList {
+				// The bodies and pacers of saved results won't be used again
+				if !aggregated {
+					if err := checkEndpoint(endpointList[i]); err != nil {
+						fatal(exitReasonInvalidInput, endpointName(endpointList[i])+": "+err.Error())
+					}
+				}
 				duration, err := time.ParseDuration(endpointList[i].Query.Duration)
 				if err != nil {
-					log.Fatal(err)
+					fatal(exitReasonInvalidInput, err)
 				}
 				sum += duration.Seconds()
 			}
+			if len(sweep) > 0 {
+				sum *= float64(len(sweep))
+			}
+			if c.IsSet("rate-percent") || c.Bool("max-workers-auto") {
+				probeDuration, err := time.ParseDuration(c.String("probe-duration"))
+				if err != nil {
+					fatal(exitReasonInvalidInput, err)
+				}
+				// Tuning the workers takes at least one probe
+				probes := countSet(c, "rate-percent", "max-workers-auto")
+				sum += probeDuration.Seconds() * float64(len(endpointList)*probes)
+			}
 
-			if !c.IsSet("quiet") {
+			if !c.IsSet("quiet") && !aggregated {
 				go showProgressBar(int(sum))
 			}
 
 			// Query each endpoint specified
+			options := queryOptions{
+				CaptureHeaders: c.Bool("headers") || c.IsSet("headers-baseline"),
+				FailureSamples: c.Int("failure-samples"),
+
+				RateLimitThreshold: c.Float64("rate-limit-threshold") / 100,
+				RateLimitBackoff:   c.Bool("rate-limit-backoff"),
+				AutoWarmup:         c.Bool("auto-warmup"),
+				Timeline:           c.IsSet("error-band") || c.Bool("rate-timeline"),
+				StatusClasses:      c.Bool("latency-by-status"),
+				RequestIDHeader:    c.String("request-id-header"),
+			}
+			if c.IsSet("histogram") {
+				buckets := strings.TrimSpace(c.String("histogram"))
+				if !strings.HasPrefix(buckets, "[") {
+					buckets = "[" + buckets + "]"
+				}
+				err := options.HistogramBuckets.UnmarshalText([]byte(buckets))
+				if err != nil {
+					fatal(exitReasonInvalidInput, err)
+				}
+			} else if c.Bool("tui") {
+				options.HistogramBuckets = tuiHistogramBuckets
+			}
+			if c.IsSet("sla") {
+				options.SLA = parseSLA(c.String("sla"))
+			}
+			if c.Bool("fail-fast") {
+				options.FailFastThreshold = c.Float64("threshold")
+			}
+			if len(sweep) > 0 {
+				results := runConnSweep(endpointList, sweep, options)
+				if c.String("conn-sweep-graph") != "-" {
+					printConnSweep(endpointList, results)
+				}
+				if c.IsSet("conn-sweep-graph") {
+					file := createOutputFile(c.String("conn-sweep-graph"))
+					createConnSweepGraph(endpointList, results).WriteTo(file)
+					file.Close()
+				}
+				return nil
+			}
+			for i := range endpointList {
+				if c.IsSet("rate-percent") {
+					applyRatePercent(&endpointList[i], c.Float64("rate-percent"), c.String("probe-duration"))
+				}
+				if c.Bool("max-workers-auto") && !aggregated {
+					tuneWorkers(&endpointList[i], c.Uint64("max-workers-ceiling"), c.String("probe-duration"))
+				}
+				if !aggregated {
+					queryAPI(&endpointList[i], options)
+				}
+				if c.Bool("percentile-ci") {
+					endpointList[i].PercentileIntervals = percentileIntervals(endpointList[i].Metrics)
+				}
+				if c.IsSet("min-rate-percent") {
+					endpointList[i].RateShortfall = checkAchievedRate(endpointList[i], c.Float64("min-rate-percent")/100)
+				}
+				// Timed out requests would breach the threshold, but the endpoint didn't answer at all
+				if c.Bool("fail-fast") && isUnreachable(endpointList[i]) {
+					return exitWith(exitReasonUnreachable, []string{endpointName(endpointList[i])},
+						"Failing fast: "+endpointName(endpointList[i])+" didn't respond to any request")
+				}
+				if c.Bool("fail-fast") {
+					p99 := durationToMs(endpointList[i].Metrics.Latencies.P99)
+					// The attack was cut short even if the final P99 is back under the threshold
+					if endpointList[i].FailedFast && p99 <= c.Float64("threshold") {
+						return exitWith(exitReasonSLOBreach, []string{endpointName(endpointList[i])},
+							"Failing fast: "+endpointName(endpointList[i])+" P99 exceeded the "+formatMs(c.Float64("threshold"))+
+								" threshold during the run, stopping it at "+formatMs(p99))
+					}
+					if p99 > c.Float64("threshold") {
+						return exitWith(exitReasonSLOBreach, []string{endpointName(endpointList[i])},
+							"Failing fast: "+endpointName(endpointList[i])+" P99 "+formatMs(p99)+
+								" exceeds the "+formatMs(c.Float64("threshold"))+" threshold")
+					}
+				}
+			}
+			environment, err := collectEnvironment(endpointList)
+			if err != nil {
+				fatal(exitReasonError, err)
+			}
 			for i := range endpointList {
-				endpointList[i].Metrics = queryAPI(endpointList[i])
+				endpointList[i].Environment = &environment
+			}
+			sortEndpoints(endpointList, c.String("sort"))
+			format := latencyFormat{Unit: c.String("latency-unit"), Precision: -1}
+			if c.IsSet("latency-precision") {
+				format.Precision = c.Int("latency-precision")
 			}
 			// Print text report
 			if c.Bool("print") {
-				printText(endpointList)
+				printText(endpointList, percentiles, c.Float64("threshold"))
 			}
 			// Create a PDF with some informative text and the graph we've just created
-			if c.IsSet("output") {
-				createPDF(endpointList, c.String("output"))
+			if c.IsSet("output") || c.Bool("estimate") {
+				graphOptions := graphOptions{
+					Threshold:      c.Float64("threshold"),
+					TopN:           c.Int("graph-top-n"),
+					TopOrder:       c.String("graph-top-order"),
+					WeightByVolume: c.Bool("graph-weight-by-volume"),
+					NoGraph:        c.Bool("no-graph"),
+					ConfigAppendix: c.Bool("config-appendix"),
+					ErrorBand:      c.Float64("error-band") / 100,
+					Events:         events,
+					RateTimeline:   c.Bool("rate-timeline"),
+					Percentiles:    defaultPercentiles,
+					YMin:           c.Float64("y-min"),
+				}
+				if percentiles != nil {
+					graphOptions.Percentiles = percentiles
+				}
+				if c.Bool("estimate") {
+					estimatePDF(endpointList, graphOptions, environment)
+				} else {
+					createPDF(endpointList, c.String("output"), graphOptions, environment)
+				}
 			}
 
 			if c.IsSet("json") {
-				printJson(endpointList)
+				printJson(endpointList, format)
+			}
+
+			if c.Bool("explain") {
+				printExplanations(endpointList, c.Float64("threshold"))
 			}
 
+			if c.IsSet("badge") {
+				file := createOutputFile(c.String("badge"))
+				createBadge(endpointList, c.Float64("threshold")).WriteTo(file)
+				file.Close()
+			}
+
+			if c.IsSet("per-endpoint-dir") {
+				writeEndpointFiles(endpointList, c.String("per-endpoint-dir"), format)
+			}
+
+			if c.IsSet("headers-baseline") {
+				printHeaderDiff(endpointList, parseEndpointsJSON(c.String("headers-baseline")))
+			}
+
+			// Export the results, giving up on all exports once the export
+			// timeout expires or rtapi is interrupted
+			exportContext, cancelExports := context.WithCancel(context.Background())
+			defer cancelExports()
+			if c.IsSet("export-timeout") {
+				var cancel context.CancelFunc
+				exportContext, cancel = context.WithTimeout(exportContext, c.Duration("export-timeout"))
+				defer cancel()
+			}
+			stopExports := interruptExports(cancelExports)
+			var failedExports []string
+			var exportOutcomes []exportOutcome
 			if c.IsSet("splunk") {
-				sendJsonToSplunk(endpointList, splunkSettings)
+				err := sendJsonToSplunk(exportContext, endpointList, splunkSettings, c.Int("export-concurrency"))
+				if err != nil {
+					log.Print("Sending results to Splunk failed: ", err)
+					failedExports = append(failedExports, "splunk")
+				}
+				exportOutcomes = append(exportOutcomes, newExportOutcome("splunk", endpointList, err))
+			}
+
+			if c.IsSet("grafana") {
+				err := sendAnnotationToGrafana(exportContext, endpointList, grafanaSettings)
+				if err != nil {
+					log.Print("Sending annotation to Grafana failed: ", err)
+					failedExports = append(failedExports, "grafana")
+				}
+				exportOutcomes = append(exportOutcomes, newExportOutcome("grafana", endpointList, err))
+			}
+
+			if c.IsSet("elastic") {
+				err := sendMetricsToElastic(exportContext, endpointList, elasticSettings)
+				if err != nil {
+					log.Print("Sending results to Elasticsearch failed: ", err)
+					failedExports = append(failedExports, "elastic")
+				}
+				exportOutcomes = append(exportOutcomes, newExportOutcome("elastic", endpointList, err))
+			}
+
+			if c.IsSet("exec-reporter") {
+				err := runExecReporter(exportContext, endpointList, c.String("exec-reporter"), format)
+				if err != nil {
+					log.Print("Running the external reporter failed: ", err)
+					failedExports = append(failedExports, "exec-reporter")
+				}
+				exportOutcomes = append(exportOutcomes, newExportOutcome("exec-reporter", endpointList, err))
+			}
+			if stopExports() {
+				printExportOutcomes(exportOutcomes)
+				return exitWith(exitReasonInterrupted, failedExports, "Interrupted while exporting the results")
+			}
+
+			if c.Bool("tui") {
+				err := runTUI(endpointList, c.Float64("threshold"))
+				if err != nil {
+					log.Print("Running the TUI failed: ", err)
+				}
+			}
+
+			if len(skipped) > 0 {
+				printSkippedEntries(skipped)
+			}
+
+			var unreachable []string
+			for i := range endpointList {
+				if isUnreachable(endpointList[i]) {
+					unreachable = append(unreachable, endpointName(endpointList[i]))
+				}
+			}
+			if len(unreachable) > 0 {
+				return exitWith(exitReasonUnreachable, unreachable,
+					"Some endpoints didn't respond to any request: "+strings.Join(unreachable, ", "))
+			}
+
+			if c.IsSet("aggregate-threshold") {
+				p99 := durationToMs(aggregateP99(endpointList))
+				threshold := c.Float64("aggregate-threshold")
+				os.Stderr.Write([]byte("Aggregate P99: " + formatMs(p99) + " (threshold " + formatMs(threshold) + ")\n"))
+				if p99 > threshold {
+					var failed []string
+					for i := range endpointList {
+						if durationToMs(endpointList[i].Metrics.Latencies.P99) > threshold {
+							failed = append(failed, endpointName(endpointList[i]))
+						}
+					}
+					// The merged traffic can breach with every endpoint under it
+					if len(failed) == 0 {
+						failed = []string{"aggregate"}
+					}
+					return exitWith(exitReasonSLOBreach, failed,
+						"Aggregate P99 "+formatMs(p99)+" exceeds the "+formatMs(threshold)+" threshold")
+				}
+			}
+
+			if c.IsSet("baseline") {
+				maxGrowth := -1.0
+				if c.IsSet("max-regression") {
+					maxGrowth = c.Float64("max-regression") / 100
+				}
+				regressed := checkRegressions(compareBaseline(endpointList, parseEndpointsJSON(c.String("baseline"))), maxGrowth)
+				if len(regressed) > 0 {
+					return exitWith(exitReasonRegression, regressed,
+						"Some endpoints regressed by more than "+formatPercent(maxGrowth)+" over the baseline: "+strings.Join(regressed, ", "))
+				}
+			}
+
+			var tooSlow []string
+			for i := range endpointList {
+				if exceedsMaxLatency(endpointList[i]) {
+					tooSlow = append(tooSlow, endpointName(endpointList[i]))
+				}
+			}
+			if len(tooSlow) > 0 {
+				return exitWith(exitReasonMaxLatency, tooSlow,
+					"Some endpoints had requests slower than their maximum latency: "+strings.Join(tooSlow, ", "))
+			}
+
+			var mismatched []string
+			for i := range endpointList {
+				if endpointList[i].StatusMismatches > 0 {
+					mismatched = append(mismatched, endpointName(endpointList[i]))
+				}
+			}
+			if len(mismatched) > 0 {
+				return exitWith(exitReasonStatusMismatch, mismatched,
+					"Some endpoints didn't return their expected status code: "+strings.Join(mismatched, ", "))
+			}
+
+			var contentMismatched []string
+			for i := range endpointList {
+				if endpointList[i].ContentTypeMismatches > 0 {
+					contentMismatched = append(contentMismatched, endpointName(endpointList[i]))
+				}
+			}
+			if len(contentMismatched) > 0 {
+				return exitWith(exitReasonContentTypeMismatch, contentMismatched,
+					"Some endpoints didn't return their expected content type: "+strings.Join(contentMismatched, ", "))
+			}
+
+			var erroring []string
+			for i := range endpointList {
+				if tooManyErrors(endpointList[i]) {
+					erroring = append(erroring, endpointName(endpointList[i]))
+				}
+			}
+			if len(erroring) > 0 {
+				return exitWith(exitReasonTooManyErrors, erroring,
+					"Some endpoints failed more requests than their maximum: "+strings.Join(erroring, ", "))
+			}
+
+			if c.Bool("fail-on-rate-shortfall") {
+				var shortfalls []string
+				for i := range endpointList {
+					if endpointList[i].RateShortfall != nil {
+						shortfalls = append(shortfalls, endpointName(endpointList[i]))
+					}
+				}
+				if len(shortfalls) > 0 {
+					return exitWith(exitReasonRateShortfall, shortfalls,
+						"Some endpoints weren't queried at their requested rate: "+strings.Join(shortfalls, ", "))
+				}
+			}
+
+			if len(failedExports) > 0 {
+				return exitWith(exitReasonExportFailure, failedExports,
+					"The benchmark completed but exporting its results failed")
 			}
 			return nil
 		},
 	}
+	// Report panics as internal errors with exit code 1
+	defer recoverInternalError()
 	err := app.Run(os.Args)
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonInvalidInput, err)
+	}
+}
+
+// Count the given output flags writing to stdout
+func countStdout(c *cli.Context, names ...string) int {
+	count := 0
+	for _, name := range names {
+		if c.String(name) == "-" {
+			count++
+		}
+	}
+	return count
+}
+
+func countSet(c *cli.Context, names ...string) int {
+	count := 0
+	for _, name := range names {
+		if c.IsSet(name) {
+			count++
+		}
 	}
+	return count
 }
 
 func parseEndpointsJSON(file string) []endpointDetails {
 	jsonFile, err := os.Open(file)
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	defer jsonFile.Close()
 
 	byteValue, err := ioutil.ReadAll(jsonFile)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
+	}
+	// JSON outputs may have been written in another latency unit
+	byteValue, err = readLatencies(byteValue)
+	if err != nil {
+		fatal(exitReasonInvalidInput, file+": "+err.Error())
 	}
 
 	var temp []endpointDetails
 	err = json.Unmarshal(byteValue, &temp)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
+	}
+	for i := range temp {
+		temp[i].LatencyUnit = ""
 	}
 	return temp
 }
@@ -198,82 +1126,126 @@ func parseEndpointsJSON(file string) []endpointDetails {
 func parseEndpointsYAML(file string) []endpointDetails {
 	yamlFile, err := os.Open(file)
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	defer yamlFile.Close()
 
 	byteValue, err := ioutil.ReadAll(yamlFile)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	var temp []endpointDetails
 	err = yaml.Unmarshal(byteValue, &temp)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	return temp
 }
 
-func parseSplunkSettingsJSON(file string) splunkSettings {
+// Parse a Splunk settings file, either holding a single settings object or
+// several named profiles, into the profiles it holds
+func parseSplunkSettingsJSON(file string) map[string]splunkSettings {
 	jsonFile, err := os.Open(file)
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	defer jsonFile.Close()
 
 	byteValue, err := ioutil.ReadAll(jsonFile)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	//log.Printf(string(byteValue))
+	var profiles map[string]splunkSettings
+	if json.Unmarshal(byteValue, &profiles) == nil {
+		return profiles
+	}
+	// Not a set of profiles, so a single settings object
 	var temp splunkSettings
 	err = json.Unmarshal(byteValue, &temp)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
 	}
-	return temp
+	return map[string]splunkSettings{defaultSplunkProfile: temp}
 }
 
-func parseSplunkSettingsYAML(file string) splunkSettings {
+func parseSplunkSettingsYAML(file string) map[string]splunkSettings {
 	yamlFile, err := os.Open(file)
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	defer yamlFile.Close()
 
 	byteValue, err := ioutil.ReadAll(yamlFile)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
 	}
+	var profiles map[string]splunkSettings
+	if yaml.Unmarshal(byteValue, &profiles) == nil {
+		return profiles
+	}
+	// Not a set of profiles, so a single settings object
 	var temp splunkSettings
 	err = yaml.Unmarshal(byteValue, &temp)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
 	}
-	return temp
+	return map[string]splunkSettings{defaultSplunkProfile: temp}
+}
+
+func parseSplunkProfiles(file string) map[string]splunkSettings {
+	if filepath.Ext(file) == ".json" {
+		return parseSplunkSettingsJSON(file)
+	} else if filepath.Ext(file) == ".yml" || filepath.Ext(file) == ".yaml" {
+		return parseSplunkSettingsYAML(file)
+	}
+	fatal(exitReasonInvalidInput, "Splunk settings file "+file+" must be a JSON or YAML file")
+	return nil
+}
+
+// Pick the Splunk settings profile to use, which can be left out if there's only one
+func selectSplunkProfile(profiles map[string]splunkSettings, name string) splunkSettings {
+	if name == "" && len(profiles) == 1 {
+		for _, settings := range profiles {
+			return settings
+		}
+	}
+	if name == "" {
+		fatal(exitReasonInvalidInput, "The Splunk settings file has several profiles, please select one with --splunk-profile")
+	}
+	settings, ok := profiles[name]
+	if !ok {
+		fatal(exitReasonInvalidInput, "Unknown Splunk profile: "+name)
+	}
+	return settings
 }
 
 func parseJSONString(value string) []endpointDetails {
 	var temp []endpointDetails
 	err := json.Unmarshal([]byte(value), &temp)
 	if err != nil {
-		panic(err)
+		fatal(exitReasonInvalidInput, err)
 	}
 	return temp
 }
 
+// The query parameters used when none are specified for an endpoint
+func defaultQuery() endpointQuery {
+	return endpointQuery{
+		Threads:     2,
+		MaxThreads:  2,
+		Connections: 10,
+		Duration:    "10s",
+		RequestRate: 500,
+	}
+}
+
 // Override the default JSON unmarshal behavior to set some default query parameters
 // if they are not specified in the input JSON
 func (details *endpointDetails) UnmarshalJSON(b []byte) error {
 	type tempDetails endpointDetails
 	temp := &tempDetails{
-		Query: endpointQuery{
-			Threads:     2,
-			MaxThreads:  2,
-			Connections: 10,
-			Duration:    "10s",
-			RequestRate: 500,
-		},
+		Query: defaultQuery(),
 	}
 	if err := json.Unmarshal(b, temp); err != nil {
 		return err
@@ -287,13 +1259,7 @@ func (details *endpointDetails) UnmarshalJSON(b []byte) error {
 func (details *endpointDetails) UnmarshalYAML(node *yaml.Node) error {
 	type tempDetails endpointDetails
 	temp := &tempDetails{
-		Query: endpointQuery{
-			Threads:     2,
-			MaxThreads:  2,
-			Connections: 10,
-			Duration:    "10s",
-			RequestRate: 500,
-		},
+		Query: defaultQuery(),
 	}
 	if err := node.Decode(temp); err != nil {
 		return err
@@ -302,37 +1268,238 @@ func (details *endpointDetails) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
-func queryAPI(endpoint endpointDetails) vegeta.Metrics {
-	rate := vegeta.Rate{
-		Freq: endpoint.Query.RequestRate,
-		Per:  time.Second,
-	}
+func queryAPI(endpoint *endpointDetails, options queryOptions) {
+	pacer := newPacer(endpoint.Query)
 	duration, err := time.ParseDuration(endpoint.Query.Duration)
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonInvalidInput, err)
 	}
-	targeter := vegeta.NewStaticTargeter(
-		vegeta.Target{
-			URL:    endpoint.Target.URL,
-			Method: endpoint.Target.Method,
-			Body:   []byte(endpoint.Target.Body),
-			Header: endpoint.Target.Header,
-		},
-	)
-	workers := vegeta.Workers(endpoint.Query.Threads)
-	maxWorkers := vegeta.MaxWorkers(endpoint.Query.MaxThreads)
+	target := endpoint.Target
+	target.Header = requestHeader(target)
+	if endpoint.Query.Expect100Continue {
+		target.Header.Set("Expect", "100-continue")
+	}
+	targeter := newTargeter(target)
+	var ids *requestIDs
+	if options.RequestIDHeader != "" {
+		ids = newRequestIDs(options.RequestIDHeader)
+		targeter = ids.Targeter(targeter)
+	}
+	// The client is built here rather than left to vegeta so its connections can be counted
+	client := newHTTPClient(endpoint.Query)
+	redirects := redirectChecker{Max: maxRedirects(endpoint.Query)}
+	client.CheckRedirect = redirects.CheckRedirect
+	if target.ClientCert != "" || target.ClientKey != "" {
+		config, err := clientTLSConfig(target)
+		if err != nil {
+			fatal(exitReasonInvalidInput, err)
+		}
+		client.Transport.(*http.Transport).TLSClientConfig = config
+	}
+	attackerOptions := []func(*vegeta.Attacker){vegeta.Client(client)}
 	connections := vegeta.Connections(endpoint.Query.Connections)
+	// Response bodies are only read as far as needed for failure samples
 	body := vegeta.MaxBody(0)
-	attacker := vegeta.NewAttacker(workers, maxWorkers, connections, body)
+	if options.FailureSamples > 0 {
+		body = vegeta.MaxBody(sampleBodySize)
+	}
+	attackerOptions = append(attackerOptions, connections, body)
+	if endpoint.Query.Chunked {
+		attackerOptions = append(attackerOptions, vegeta.ChunkedBody(true))
+	}
+	var pipelining *pipeliningTransport
+	if endpoint.Query.Pipeline > 1 {
+		if endpoint.Query.DisableKeepAlive || endpoint.Query.Expect100Continue {
+			fatal(exitReasonInvalidInput, "Pipelining can't be combined with disable_keep_alive or expect_100_continue")
+		}
+		// Vegeta's options are applied to the standard transport, which the
+		// pipelining transport takes its settings from once the attack starts
+		pipelining = newPipeliningTransport(client.Transport.(*http.Transport), endpoint.Query.Pipeline)
+		pipelined := *client
+		pipelined.Transport = pipelining
+		client = &pipelined
+	}
+	if endpoint.Query.H2C {
+		if endpoint.Query.Pipeline > 1 || !strings.HasPrefix(target.URL, "http://") {
+			fatal(exitReasonInvalidInput, "h2c needs an http:// URL and can't be combined with pipelining")
+		}
+		h2c := *client
+		h2c.Transport = newH2CTransport(client.Transport.(*http.Transport))
+		client = &h2c
+	}
+	var reuse connectionCounter
+	var protocols protocolCounter
+	attackerOptions = append(attackerOptions, vegeta.Client(reuse.Client(protocols.Client(client))))
+	var rateLimits rateLimitDetector
+	var paused time.Duration
 	var metrics vegeta.Metrics
-	for response := range attacker.Attack(targeter, rate, duration, "") {
-		metrics.Add(response)
+	monitor := sloMonitor{Threshold: options.FailFastThreshold}
+	breached := false
+	slaUnder := make([]uint64, len(options.SLA))
+	var warmup warmupDetector
+	var timeline timelineRecorder
+	var statusClasses statusClassRecorder
+	var histogram *vegeta.Histogram
+	if len(options.HistogramBuckets) > 0 {
+		histogram = &vegeta.Histogram{Buckets: options.HistogramBuckets}
+	}
+	// The accumulators of the responses after a warm-up detected by
+	// --auto-warmup, replacing those of every response once it's detected
+	var steady vegeta.Metrics
+	var spread, steadySpread latencySpread
+	var steadyHistogram *vegeta.Histogram
+	if histogram != nil && options.AutoWarmup {
+		steadyHistogram = &vegeta.Histogram{Buckets: options.HistogramBuckets}
+	}
+	var steadyStatusClasses statusClassRecorder
+	var contentTypeMismatches, steadyContentTypeMismatches uint64
+	steadySLAUnder := make([]uint64, len(options.SLA))
+	for _, stage := range attackStages(endpoint.Query, pacer, duration) {
+		if breached {
+			break
+		}
+		stagePacer := stage.Pacer
+		var backoff *rateLimitPacer
+		if options.RateLimitBackoff {
+			backoff = &rateLimitPacer{Pacer: stagePacer}
+			stagePacer = backoff
+		}
+		workers := vegeta.Workers(stage.Workers)
+		maxWorkers := vegeta.MaxWorkers(stage.MaxWorkers)
+		attacker := vegeta.NewAttacker(append(attackerOptions, workers, maxWorkers)...)
+		var stageMetrics vegeta.Metrics
+		began := time.Now()
+		for response := range attacker.Attack(targeter, stagePacer, stage.Duration, attackName(*endpoint)) {
+			mismatch := endpoint.ExpectContentType != "" && checkContentType(response, endpoint.ExpectContentType)
+			if mismatch {
+				contentTypeMismatches++
+			}
+			metrics.Add(response)
+			spread.Add(response)
+			if histogram != nil {
+				histogram.Add(response)
+			}
+			if options.Timeline {
+				timeline.Add(response)
+			}
+			if options.StatusClasses {
+				statusClasses.Add(response)
+			}
+			steadyState := options.AutoWarmup && warmup.Add(response)
+			if steadyState {
+				steady.Add(response)
+				steadySpread.Add(response)
+				if mismatch {
+					steadyContentTypeMismatches++
+				}
+				if steadyHistogram != nil {
+					steadyHistogram.Add(response)
+				}
+				if options.StatusClasses {
+					steadyStatusClasses.Add(response)
+				}
+			}
+			for j := range options.SLA {
+				if response.Error == "" && response.Latency <= options.SLA[j].Latency {
+					slaUnder[j]++
+					if steadyState {
+						steadySLAUnder[j]++
+					}
+				}
+			}
+			// Once a warm-up is detected, only the steady state is monitored
+			monitored := &metrics
+			if steadyState {
+				monitored = &steady
+			}
+			if options.FailFastThreshold > 0 && !breached && monitor.Breached(monitored) {
+				breached = true
+				endpoint.FailedFast = true
+				attacker.Stop()
+			}
+			if endpoint.Query.WorkerRamp {
+				stageMetrics.Add(response)
+			}
+			if rateLimits.Add(response) && backoff != nil {
+				now := time.Now()
+				backoff.Pause(now.Sub(began), parseRetryAfter(response.Headers.Get("Retry-After"), now))
+			}
+			// Keep the headers of the first response that made it back to us
+			if options.CaptureHeaders && endpoint.ResponseHeaders == nil && response.Headers != nil {
+				endpoint.ResponseHeaders = response.Headers
+			}
+			if response.Error != "" && len(endpoint.FailureSamples) < options.FailureSamples {
+				endpoint.FailureSamples = append(endpoint.FailureSamples, newFailureSample(target, response))
+			}
+		}
+		if backoff != nil {
+			paused += backoff.Paused()
+		}
+		if endpoint.Query.WorkerRamp {
+			stageMetrics.Close()
+			endpoint.RampStages = append(endpoint.RampStages, rampStage{
+				Workers: stage.Workers,
+				Rate:    stageMetrics.Rate,
+				P99:     stageMetrics.Latencies.P99,
+			})
+		}
 	}
 	metrics.Close()
-	return metrics
+	endpoint.Metrics = metrics
+	endpoint.LatencyStdDev, endpoint.LatencyCV = spread.Result()
+	if ids != nil {
+		endpoint.RequestIDs = ids.Result()
+	}
+	endpoint.Histogram = histogram
+	endpoint.ConnectionReuse = reuse.Result()
+	endpoint.Protocols = protocols.Result()
+	if pipelining != nil {
+		pipelining.CloseIdleConnections()
+		endpoint.Pipelining = pipelining.Result()
+	}
+	endpoint.Redirects = redirects.Result()
+	if options.Timeline {
+		endpoint.Timeline = timeline.Result()
+	}
+	if options.StatusClasses {
+		endpoint.StatusClasses = statusClasses.Result()
+	}
+	endpoint.ContentTypeMismatches = contentTypeMismatches
+	slaRequests := metrics.Requests
+	if options.AutoWarmup {
+		endpoint.Warmup = warmup.Result(metrics.Requests, steady.Requests)
+		if endpoint.Warmup.Stable {
+			steady.Close()
+			endpoint.Metrics = steady
+			endpoint.LatencyStdDev, endpoint.LatencyCV = steadySpread.Result()
+			if steadyHistogram != nil {
+				endpoint.Histogram = steadyHistogram
+			}
+			if options.StatusClasses {
+				endpoint.StatusClasses = steadyStatusClasses.Result()
+			}
+			slaUnder, slaRequests = steadySLAUnder, steady.Requests
+			endpoint.ContentTypeMismatches = steadyContentTypeMismatches
+		}
+	}
+	discountContentTypeMismatches(&endpoint.Metrics, endpoint.ContentTypeMismatches)
+	// Counted from the success ratio, so over the same requests as the other checks
+	if endpoint.MaxErrors != nil {
+		endpoint.Errors = countErrors(endpoint.Metrics)
+	}
+	if endpoint.ExpectStatus != 0 {
+		endpoint.StatusMismatches = countStatusMismatches(*endpoint)
+	}
+	if len(options.SLA) > 0 {
+		endpoint.SLA = slaResults(options.SLA, slaUnder, slaRequests)
+	}
+	endpoint.RateLimited = rateLimits.Result(metrics.Requests, options.RateLimitThreshold)
+	if endpoint.RateLimited != nil {
+		endpoint.RateLimited.Paused = paused
+	}
 }
 
-func printText(endpoints []endpointDetails) {
+func printText(endpoints []endpointDetails, percentiles []float64, threshold float64) {
 	os.Stdout.Write([]byte("====================================\n"))
 	os.Stdout.Write([]byte("NGINX — Real-Time API Latency Report\n"))
 	os.Stdout.Write([]byte("====================================\n\n"))
@@ -342,68 +1509,242 @@ func printText(endpoints []endpointDetails) {
 			"it is of the upmost importance for consumers to have positive experiences.\n\n",
 		"Therefore, at NGINX, we define a real-time API as one that can process end-to-end API calls in 30ms or less (see " +
 			"\"https://www.nginx.com/blog/how-real-time-apis-power-our-lives\" for more information).\n\n",
+		"This report considers an API real time if its latency at the 99th percentile is " + formatMs(threshold) + " or less.\n\n",
 		"To get started, let’s assess how your API endpoints stack up.\n\n",
 		"Learn more, talk to an NGINX expert, and discover how NGINX can help you on " +
 			"your journey towards real-time APIs at \"https://www.nginx.com/real-time-api\"\n",
 	}
 	os.Stdout.Write([]byte(text[0]))
 	os.Stdout.Write([]byte(text[1]))
-	os.Stdout.Write([]byte(text[2]))
+	if threshold != defaultThreshold {
+		os.Stdout.Write([]byte(text[2]))
+	}
+	os.Stdout.Write([]byte(text[3]))
 	for i := range endpoints {
 		reporter := vegeta.NewTextReporter(&endpoints[i].Metrics)
+		if percentiles != nil {
+			reporter = newPercentilesTextReporter(&endpoints[i].Metrics, percentiles)
+		}
 		os.Stdout.Write([]byte("------------------------------------\n"))
 		os.Stdout.Write([]byte("API Endpoint: " + endpoints[i].Target.URL + "\n"))
+		if owner := endpointOwner(endpoints[i]); owner != "" {
+			os.Stdout.Write([]byte("Owner: " + owner + "\n"))
+		}
 		os.Stdout.Write([]byte("------------------------------------\n"))
+		if endpoints[i].DiscoveredMaxRate > 0 {
+			os.Stdout.Write([]byte("Discovered max rate: " + strconv.FormatFloat(endpoints[i].DiscoveredMaxRate, 'f', 2, 64) +
+				" req/s, applied rate: " + strconv.Itoa(endpoints[i].Query.RequestRate) + " req/s\n"))
+		}
+		if endpoints[i].WorkerTuning != nil {
+			printWorkerTuning(endpoints[i].WorkerTuning)
+		}
+		if endpoints[i].StatusMismatches > 0 {
+			os.Stdout.Write([]byte(statusMismatchWarning(endpoints[i]) + "\n"))
+		}
+		if endpoints[i].ContentTypeMismatches > 0 {
+			os.Stdout.Write([]byte(contentTypeMismatchWarning(endpoints[i]) + "\n"))
+		}
+		if tooManyErrors(endpoints[i]) {
+			os.Stdout.Write([]byte(maxErrorsWarning(endpoints[i]) + "\n"))
+		}
+		if exceedsMaxLatency(endpoints[i]) {
+			os.Stdout.Write([]byte(maxLatencyWarning(endpoints[i]) + "\n"))
+		}
 		reporter.Report(os.Stdout)
+		if endpoints[i].LatencyStdDev > 0 {
+			printLatencySpread(endpoints[i])
+		}
+		if len(endpoints[i].PercentileIntervals) > 0 {
+			printPercentileIntervals(endpoints[i].PercentileIntervals)
+		}
+		if len(endpoints[i].RampStages) > 0 {
+			printRampStages(endpoints[i].RampStages)
+		}
+		if endpoints[i].Histogram != nil {
+			vegeta.NewHistogramReporter(endpoints[i].Histogram).Report(os.Stdout)
+		}
+		if len(endpoints[i].StatusClasses) > 0 {
+			printStatusClasses(endpoints[i].StatusClasses)
+		}
+		if endpoints[i].ConnectionReuse != nil {
+			printConnectionReuse(endpoints[i].ConnectionReuse)
+		}
+		if len(endpoints[i].Protocols) > 0 {
+			printProtocols(endpoints[i].Protocols)
+		}
+		if endpoints[i].Pipelining != nil {
+			printPipelining(endpoints[i].Pipelining)
+		}
+		if endpoints[i].RequestIDs != nil {
+			printRequestIDs(endpoints[i].RequestIDs)
+		}
+		if endpoints[i].Warmup != nil {
+			printWarmup(endpoints[i].Warmup)
+		}
+		for _, result := range endpoints[i].SLA {
+			os.Stdout.Write([]byte(result.String() + "\n"))
+		}
+		if endpoints[i].RateShortfall != nil {
+			os.Stdout.Write([]byte(rateShortfallWarning(endpoints[i]) + "\n"))
+		}
+		if len(endpoints[i].Timeline) > 0 {
+			printRateTimeline(endpoints[i])
+		}
+		if endpoints[i].Redirects != nil {
+			os.Stdout.Write([]byte(redirectWarning(endpoints[i]) + "\n"))
+		}
+		if endpoints[i].RateLimited != nil {
+			os.Stdout.Write([]byte(rateLimitWarning(endpoints[i]) + "\n"))
+		}
+		if len(endpoints[i].FailureSamples) > 0 {
+			printFailureSamples(endpoints[i].FailureSamples)
+		}
 		os.Stdout.Write([]byte("------------------------------------\n\n"))
 	}
-	os.Stdout.Write([]byte(text[3]))
+	printHostLoads(endpoints)
+	os.Stdout.Write([]byte("\n" + text[4]))
 }
 
-func sendJsonToSplunk(endpoints []endpointDetails, splunkSettings splunkSettings) {
-	for i := range endpoints {
-		now := time.Now()
-		name, err := os.Hostname()
+// Send an event per endpoint, at most concurrency at once
+func sendJsonToSplunk(ctx context.Context, endpoints []endpointDetails, splunkSettings splunkSettings, concurrency int) error {
+	header := http.Header{}
+	header.Set("Authorization", splunkSettings.Authkey)
+	return sendConcurrently(len(endpoints), concurrency, func(i int) error {
+		var splunkMessage = splunkEvent{splunkEventTime(endpoints[i], splunkSettings.TimeSource), endpoints[i].Environment.Hostname, splunkSettings.Source, endpoints[i]}
+		jsonInfo, err := json.Marshal(splunkMessage)
 		if err != nil {
-			panic(err)
+			return err
 		}
+		_, err = postWithRetry(ctx, splunkSettings.Url, header, jsonInfo)
+		if err != nil {
+			return errors.New(endpointName(endpoints[i]) + ": " + err.Error())
+		}
+		return nil
+	})
+}
 
-		var splunkMessage = splunkEvent{now.Unix(), name, splunkSettings.Source, endpoints[i]}
-		jsonInfo, _ := json.Marshal(splunkMessage)
-		var jsonStr = []byte(jsonInfo)
-
-		//log.Print(splunkSettings.Url)
-		//log.Print(splunkSettings.Authkey)
+// The time a Splunk event is stamped with, defaulting to when the endpoint's attack ended
+func splunkEventTime(endpoint endpointDetails, timeSource string) int64 {
+	switch timeSource {
+	case "start":
+		return endpoint.Metrics.Earliest.Unix()
+	case "", "end":
+		return endpoint.Metrics.End.Unix()
+	case "send":
+		return time.Now().Unix()
+	default:
+		fatal(exitReasonInvalidInput, "Unknown Splunk time source: "+timeSource)
+	}
+	return 0
+}
 
-		req, err := http.NewRequest("POST", splunkSettings.Url, bytes.NewBuffer(jsonStr))
+// Stream the results as a JSON array, encoding one endpoint at a time rather
+// than marshaling the whole array in memory
+func printJson(endpoints []endpointDetails, format latencyFormat) {
+	err := writeJSONArray(os.Stdout, endpoints, format)
+	if err != nil {
+		fatal(exitReasonError, err)
+	}
+}
 
-		req.Header.Add("Authorization", splunkSettings.Authkey)
-		req.Header.Set("Content-Type", "application/json")
+func writeJSONArray(w io.Writer, endpoints []endpointDetails, format latencyFormat) error {
+	if _, err := w.Write([]byte("[")); err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	for i := range endpoints {
+		if i > 0 {
+			if _, err := w.Write([]byte(",")); err != nil {
+				return err
+			}
+		}
+		value, err := format.apply(endpoints[i])
+		if err != nil {
+			return err
+		}
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte("]"))
+	return err
+}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+// Write the json results of each endpoint to its own file, named after the endpoint
+func writeEndpointFiles(endpoints []endpointDetails, dir string, format latencyFormat) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		fatal(exitReasonError, err)
+	}
+	used := make(map[string]int)
+	for i := range endpoints {
+		name := sanitizeFileName(endpointName(endpoints[i]))
+		// Endpoints sharing a name get a numeric suffix rather than overwriting each other
+		used[name]++
+		if used[name] > 1 {
+			name += "_" + strconv.Itoa(used[name])
+		}
+		value, err := format.apply(endpoints[i])
 		if err != nil {
-			panic(err)
+			fatal(exitReasonError, err)
 		}
-		defer resp.Body.Close()
-		body, err := ioutil.ReadAll(resp.Body)
-		// Log the request body
-		bodyString := string(body)
-		log.Print(bodyString)
+		jsonInfo, err := json.Marshal(value)
 		if err != nil {
-			log.Printf("Reading body failed: %s", err)
-			return
+			fatal(exitReasonError, err)
 		}
+		err = ioutil.WriteFile(filepath.Join(dir, name+".json"), jsonInfo, 0644)
+		if err != nil {
+			fatal(exitReasonError, err)
+		}
+	}
+}
+
+// The name of an endpoint used in reports, falling back to its URL
+func endpointName(endpoint endpointDetails) string {
+	if endpoint.Name != "" {
+		return endpoint.Name
 	}
+	return endpoint.Target.URL
 }
 
-func printJson(endpoints []endpointDetails) {
-	jsonInfo, _ := json.Marshal(endpoints)
-	os.Stdout.Write(jsonInfo)
+func attackName(endpoint endpointDetails) string {
+	if endpoint.AttackName != "" {
+		return endpoint.AttackName
+	}
+	return endpointName(endpoint)
+}
 
+// Replace any character that isn't safe to use in a file name
+func sanitizeFileName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+	sanitized = strings.Trim(sanitized, "._")
+	if sanitized == "" {
+		return "endpoint"
+	}
+	return sanitized
 }
 
-func createPDF(endpoints []endpointDetails, output string) {
+func createPDF(endpoints []endpointDetails, output string, graphOptions graphOptions, environment runEnvironment) {
+	pdf := buildPDF(endpoints, graphOptions, environment)
+	file := createOutputFile(output)
+	err := pdf.OutputAndClose(file)
+	if err != nil {
+		fatal(exitReasonError, err)
+	}
+	os.Stderr.Write([]byte("PDF report generated successfully!\n"))
+}
+
+// Garbage collection target while laying out the PDF report, as a
+// percentage of the live heap, instead of Go's default of 100
+const pdfGCPercent = 20
+
+// Lay out the PDF report, ready to be written
+func buildPDF(endpoints []endpointDetails, graphOptions graphOptions, environment runEnvironment) *gofpdf.Fpdf {
 	text := [...]string{
 		"<center><b>NGINX — Real-Time API Latency Report</b></center>",
 		"<b>Why API Performance Matters</b>",
@@ -435,26 +1776,36 @@ func createPDF(endpoints []endpointDetails, output string) {
 			"each of the target API endpoints you listed and created an " +
 			"<a href=\"https://hdrhistogram.github.io/HdrHistogram/\">Hdr Histogram</a> graph " +
 			"that shows the latency of your API endpoints. Ideally, the latency at the 99th percentile " +
-			"(<b>99%</b> on the graph) is less than 30ms for your API to be considered real time.",
-		"Is your API’s latency below 30ms? We can help you improve it no matter where it is!",
+			"(<b>99%</b> on the graph) is less than " + formatMs(graphOptions.Threshold) + " for your API to be considered real time.",
+		"Is your API’s latency below " + formatMs(graphOptions.Threshold) + "? We can help you improve it no matter where it is!",
 		"Learn more, talk to an NGINX expert, and discover how NGINX can help you on " +
 			"your journey towards real-time APIs at <a href=\"https://www.nginx.com/real-time-api\">" +
 			"https://www.nginx.com/real-time-api</a>",
+		"We have run a simple HTTP benchmark using the query parameters you specified on " +
+			"each of the target API endpoints you listed and summarized the latency of your " +
+			"API endpoints in the table below. Ideally, the latency at the 99th percentile " +
+			"(<b>99%</b> in the table) is less than " + formatMs(graphOptions.Threshold) + " for your API to be considered real time.",
 	}
 
+	// Rendering the graphs allocates a lot of short lived memory, mostly
+	// font faces for every label, so collect it eagerly rather than letting
+	// the heap double, starting from the garbage left by the queries
+	defer debug.SetGCPercent(debug.SetGCPercent(pdfGCPercent))
+	debug.FreeOSMemory()
+
 	// Pack binary data into the go binary
 	box := packr.New("NGINX", "./data")
 	arialBytes, err := box.Find("arial.ttf")
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonError, err)
 	}
 	arialItalicBytes, err := box.Find("arial_italic.ttf")
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonError, err)
 	}
 	arialBoldBytes, err := box.Find("arial_bold.ttf")
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonError, err)
 	}
 
 	pdf := gofpdf.New("P", "mm", "A4", "")
@@ -464,6 +1815,11 @@ func createPDF(endpoints []endpointDetails, output string) {
 	pdf.AddUTF8FontFromBytes("ArialTrue", "I", arialItalicBytes)
 	pdf.AddUTF8FontFromBytes("ArialTrue", "B", arialBoldBytes)
 	pdf.SetFont("ArialTrue", "", 16)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("ArialTrue", "I", 7)
+		pdf.CellFormat(0, 10, environment.String(), "", 0, "C", false, 0, "")
+	})
 	pt := pdf.PointConvert(6)
 	html := pdf.HTMLBasicNew()
 
@@ -473,7 +1829,7 @@ func createPDF(endpoints []endpointDetails, output string) {
 	}
 	logoBytes, err := box.Find("nginx_logo.png")
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonError, err)
 	}
 	logo := bytes.NewReader(logoBytes)
 	pdf.RegisterImageOptionsReader("logo", options, logo)
@@ -507,37 +1863,190 @@ func createPDF(endpoints []endpointDetails, output string) {
 	pdf.SetFontSize(10)
 	_, lineHt = pdf.GetFontSize()
 	lineHt *= lineSpacing
-	html.Write(lineHt, text[6])
-	pdf.Ln(lineHt + pt)
+	if graphOptions.NoGraph {
+		html.Write(lineHt, text[9])
+		pdf.Ln(lineHt + pt)
+		writeLatencyTable(pdf, endpoints, lineHt, graphOptions.Percentiles)
+		pdf.Ln(pt)
+	} else {
+		html.Write(lineHt, text[6])
+		pdf.Ln(lineHt + pt)
+
+		// Create a graph with all the endpoint query results
+		buffer := createGraph(endpoints, graphOptions)
+		graph := bytes.NewReader(buffer.Bytes())
+		pdf.RegisterImageOptionsReader("graph", options, graph)
+		pdf.ImageOptions("graph", 45, 0, 120, 120, true, options, 0, "")
+
+		// Tie latency spikes to errors on a graph over time
+		if graphOptions.ErrorBand > 0 {
+			timeline := bytes.NewReader(createTimelineGraph(endpoints, graphOptions.ErrorBand, graphOptions.Events).Bytes())
+			pdf.RegisterImageOptionsReader("timeline", options, timeline)
+			pdf.ImageOptions("timeline", 30, 0, 150, 100, true, options, 0, "")
+		}
+		// Show whether the load generator stalled partway through
+		if graphOptions.RateTimeline {
+			rates := bytes.NewReader(createRateTimelineGraph(endpoints, graphOptions.Events).Bytes())
+			pdf.RegisterImageOptionsReader("rate-timeline", options, rates)
+			pdf.ImageOptions("rate-timeline", 30, 0, 150, 100, true, options, 0, "")
+		}
+	}
+
+	// Route the failing endpoints to their owners
+	for i := range endpoints {
+		if owner := endpointOwner(endpoints[i]); owner != "" && !endpointPassed(endpoints[i], graphOptions.Threshold) {
+			html.Write(lineHt, "<b>"+endpointName(endpoints[i])+"</b> failed, owned by "+owner)
+			pdf.Ln(lineHt + pt)
+		}
+	}
+
+	// Fail endpoints which didn't return their expected status code
+	for i := range endpoints {
+		if endpoints[i].StatusMismatches > 0 {
+			html.Write(lineHt, "<b>"+statusMismatchWarning(endpoints[i])+"</b>")
+			pdf.Ln(lineHt + pt)
+		}
+		if endpoints[i].ContentTypeMismatches > 0 {
+			html.Write(lineHt, "<b>"+contentTypeMismatchWarning(endpoints[i])+"</b>")
+			pdf.Ln(lineHt + pt)
+		}
+		if tooManyErrors(endpoints[i]) {
+			html.Write(lineHt, "<b>"+maxErrorsWarning(endpoints[i])+"</b>")
+			pdf.Ln(lineHt + pt)
+		}
+		if exceedsMaxLatency(endpoints[i]) {
+			html.Write(lineHt, "<b>"+maxLatencyWarning(endpoints[i])+"</b>")
+			pdf.Ln(lineHt + pt)
+		}
+	}
+
+	// State whether each endpoint met the SLA targets
+	for i := range endpoints {
+		if len(endpoints[i].SLA) > 0 {
+			results := make([]string, len(endpoints[i].SLA))
+			for j, result := range endpoints[i].SLA {
+				results[j] = result.String()
+			}
+			html.Write(lineHt, "<b>"+endpointName(endpoints[i])+"</b>: "+strings.Join(results, "; "))
+			pdf.Ln(lineHt + pt)
+		}
+	}
 
-	// Create a graph with all the endpoint query results
-	buffer := createGraph(endpoints)
-	graph := bytes.NewReader(buffer.Bytes())
-	pdf.RegisterImageOptionsReader("graph", options, graph)
-	pdf.ImageOptions("graph", 45, 0, 120, 120, true, options, 0, "")
+	// Note how far the tail percentiles can be trusted
+	for i := range endpoints {
+		if intervals := endpoints[i].PercentileIntervals; len(intervals) > 0 {
+			note := "<b>" + endpointName(endpoints[i]) + "</b>: " + percentileIntervalsSummary(intervals)
+			if uncertainPercentiles(intervals) {
+				note = "<b>WARNING: " + endpointName(endpoints[i]) + " had too few requests for its tail latency to be reliable</b>: " +
+					percentileIntervalsSummary(intervals)
+			}
+			html.Write(lineHt, note)
+			pdf.Ln(lineHt + pt)
+		}
+	}
+
+	// Flag inconsistent latency, which the percentiles alone don't describe
+	for i := range endpoints {
+		if endpoints[i].LatencyStdDev > 0 {
+			html.Write(lineHt, "<b>"+endpointName(endpoints[i])+"</b>: "+latencySpreadSummary(endpoints[i]))
+			pdf.Ln(lineHt + pt)
+		}
+	}
+
+	// Warn about results skewed by rate limiting or a load generator bottleneck
+	for i := range endpoints {
+		if endpoints[i].RateLimited != nil {
+			html.Write(lineHt, "<b>"+rateLimitWarning(endpoints[i])+"</b>")
+			pdf.Ln(lineHt + pt)
+		}
+		if endpoints[i].RateShortfall != nil {
+			html.Write(lineHt, "<b>"+rateShortfallWarning(endpoints[i])+"</b>")
+			pdf.Ln(lineHt + pt)
+		}
+	}
 
 	html.Write(lineHt, text[7])
 	pdf.Ln(lineHt + pt)
 	html.Write(lineHt, text[8])
 	pdf.Ln(lineHt + pt)
 
-	err = pdf.OutputFileAndClose(output)
+	if graphOptions.ConfigAppendix {
+		writeConfigAppendix(pdf, endpoints, environment)
+	}
+	return pdf
+}
+
+// Create the file an output should be written to, treating "-" as stdout
+func createOutputFile(output string) io.WriteCloser {
+	if output == "-" {
+		return nopWriteCloser{os.Stdout}
+	}
+	file, err := os.Create(output)
 	if err != nil {
-		log.Fatal(err)
+		fatal(exitReasonError, err)
 	}
-	os.Stdout.Write([]byte("PDF report generated successfully!\n"))
+	return file
+}
+
+type nopWriteCloser struct {
+	io.Writer
 }
 
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// Progress is written to stderr to keep stdout free for the report outputs
 func showProgressBar(sum int) {
-	os.Stdout.Write([]byte("rtapi will take " + strconv.Itoa(sum) + " seconds to run\n"))
-	uiprogress.Start()
-	progressBar := uiprogress.AddBar(sum * 10).AppendCompleted().PrependElapsed()
+	os.Stderr.Write([]byte("rtapi will take " + strconv.Itoa(sum) + " seconds to run\n"))
+	progress := uiprogress.New()
+	progress.SetOut(os.Stderr)
+	progress.Start()
+	progressBar := progress.AddBar(sum * 10).AppendCompleted().PrependElapsed()
 	for progressBar.Incr() {
 		time.Sleep(time.Second / 10)
 	}
 }
 
-func createGraph(endpoints []endpointDetails) *bytes.Buffer {
+// Opacity of an endpoint's graph line, scaled by its share of the most
+// requests sent to any plotted endpoint but never below 25% to stay visible
+func volumeOpacity(endpoints []endpointDetails, i int) float64 {
+	var most uint64
+	for j := range endpoints {
+		if endpoints[j].Metrics.Requests > most {
+			most = endpoints[j].Metrics.Requests
+		}
+	}
+	if most == 0 {
+		return 1
+	}
+	return 0.25 + 0.75*float64(endpoints[i].Metrics.Requests)/float64(most)
+}
+
+func fadeColor(c color.Color, opacity float64) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: uint8(opacity * 255)}
+}
+
+// Select the endpoints to plot, keeping only the top N by latency at 99% if requested
+func selectGraphEndpoints(endpoints []endpointDetails, options graphOptions) []endpointDetails {
+	if options.TopN <= 0 || options.TopN >= len(endpoints) {
+		return endpoints
+	}
+	selected := make([]endpointDetails, len(endpoints))
+	copy(selected, endpoints)
+	sort.SliceStable(selected, func(i, j int) bool {
+		if options.TopOrder == "best" {
+			return selected[i].Metrics.Latencies.P99 < selected[j].Metrics.Latencies.P99
+		}
+		return selected[i].Metrics.Latencies.P99 > selected[j].Metrics.Latencies.P99
+	})
+	return selected[:options.TopN]
+}
+
+func createGraph(endpoints []endpointDetails, options graphOptions) *bytes.Buffer {
+	total := len(endpoints)
+	endpoints = selectGraphEndpoints(endpoints, options)
 	// Rearrange HdrHistogram data to plottable data
 	var stringArray [][]string
 	var points []plotter.XYs
@@ -553,11 +2062,11 @@ func createGraph(endpoints []endpointDetails) *bytes.Buffer {
 			if len(values) == 4 {
 				x, err := strconv.ParseFloat(values[3], 64)
 				if err != nil {
-					log.Fatal(err)
+					fatal(exitReasonError, err)
 				}
 				y, err := strconv.ParseFloat(values[0], 64)
 				if err != nil {
-					log.Fatal(err)
+					fatal(exitReasonError, err)
 				}
 				points[i][j].X = x
 				points[i][j].Y = y
@@ -569,6 +2078,9 @@ func createGraph(endpoints []endpointDetails) *bytes.Buffer {
 	if err != nil {
 		panic(err)
 	}
+	if len(endpoints) < total {
+		p.Title.Text = "Top " + strconv.Itoa(len(endpoints)) + " " + options.TopOrder + " of " + strconv.Itoa(total) + " endpoints by latency at 99%"
+	}
 	p.X.Label.Text = "Percentile (%)"
 	p.X.Label.TextStyle.Font.Size = vg.Length(15)
 	p.X.Scale = plot.LogScale{}
@@ -577,7 +2089,7 @@ func createGraph(endpoints []endpointDetails) *bytes.Buffer {
 	p.Y.Label.TextStyle.Font.Size = vg.Length(15)
 	p.Y.Label.Padding = vg.Length(-20)
 	p.Y.Min = 0
-	p.Y.Tick.Marker = customYTicks{}
+	p.Y.Tick.Marker = customYTicks{Threshold: options.Threshold, Zoomed: options.YMin > 0}
 	p.Add(plotter.NewGrid())
 
 	// Plot the Hdr Histogram for each API endpoint
@@ -586,16 +2098,34 @@ func createGraph(endpoints []endpointDetails) *bytes.Buffer {
 		if err != nil {
 			panic(err)
 		}
-		// Start at +1 to skip the red color (and avoid confusion with the 30ms threshold line)
-		lpLine.Color = plotutil.Color(i + 1)
+		// Start at +1 to skip the red color (and avoid confusion with the real-time threshold line)
+		lineColor := plotutil.Color(i + 1)
+		legend := endpoints[i].Target.URL
+		if options.WeightByVolume {
+			lineColor = fadeColor(lineColor, volumeOpacity(endpoints, i))
+			legend += " (" + strconv.FormatUint(endpoints[i].Metrics.Requests, 10) + " requests)"
+		}
+		lpLine.Color = lineColor
 		lpLine.Dashes = plotutil.Dashes(i + 1)
-		lpPoints.Color = plotutil.Color(i + 1)
+		lpPoints.Color = lineColor
 		lpPoints.Shape = plotutil.Shape(i + 1)
 		p.Add(lpLine, lpPoints)
-		p.Legend.Add(endpoints[i].Target.URL, [2]plot.Thumbnailer{lpLine, lpPoints}[0], [2]plot.Thumbnailer{lpLine, lpPoints}[1])
+		p.Legend.Add(legend, [2]plot.Thumbnailer{lpLine, lpPoints}[0], [2]plot.Thumbnailer{lpLine, lpPoints}[1])
+	}
+	// Zoom in once the data is added, as adding it extends the axis to the
+	// lowest latency
+	if options.YMin > 0 {
+		p.Y.Min = options.YMin
+		if p.Y.Max <= p.Y.Min {
+			p.Y.Max = p.Y.Min + 1
+		}
 	}
 	// Label the latency at 99% for each API endpoint
 	for i := range endpoints {
+		// Lines are clipped to the axis but labels aren't
+		if durationToMs(endpoints[i].Metrics.Latencies.P99) < p.Y.Min {
+			continue
+		}
 		lineX, err := plotter.NewLine(
 			plotter.XYs{
 				plotter.XY{
@@ -621,13 +2151,13 @@ func createGraph(endpoints []endpointDetails) *bytes.Buffer {
 		p.Add(lineX)
 		labels, err := plotter.NewLabels(
 			plotter.XYLabels{
-				plotter.XYs{
+				XYs: plotter.XYs{
 					plotter.XY{
 						X: 100,
 						Y: float64(float64(endpoints[i].Metrics.Latencies.P99) / 1000000),
 					},
 				},
-				[]string{
+				Labels: []string{
 					strconv.FormatFloat(float64(endpoints[i].Metrics.Latencies.P99)/1000000, 'f', 3, 64) + "ms @ 99%",
 				},
 			},
@@ -639,30 +2169,30 @@ func createGraph(endpoints []endpointDetails) *bytes.Buffer {
 		labels.TextStyle[0].Font.Size = vg.Length(14)
 		p.Add(labels)
 	}
-	// Add a line to highlight the 30ms and 99% thresholds
-	line30ms, err := plotter.NewLine(
+	// Add a line to highlight the real-time and 99% thresholds
+	lineThreshold, err := plotter.NewLine(
 		plotter.XYs{
 			plotter.XY{
 				X: 1,
-				Y: 30,
+				Y: options.Threshold,
 			},
 			plotter.XY{
 				X: 10000000,
-				Y: 30,
+				Y: options.Threshold,
 			},
 		},
 	)
 	if err != nil {
 		panic(err)
 	}
-	line30ms.LineStyle = draw.LineStyle{
+	lineThreshold.LineStyle = draw.LineStyle{
 		Width: vg.Length(1),
 		Dashes: []vg.Length{
 			vg.Length(4),
 		},
 		DashOffs: vg.Length(8),
 	}
-	p.Add(line30ms)
+	p.Add(lineThreshold)
 	line99, err := plotter.NewLine(
 		plotter.XYs{
 			plotter.XY{
@@ -736,11 +2266,40 @@ func (customXTicks) Ticks(min, max float64) []plot.Tick {
 	}
 }
 
-type customYTicks struct{}
+// Step between the regular latency ticks, in milliseconds
+const yTickStep = 50
+
+// Regular ticks closer than this fraction of a step to the real-time threshold
+// are dropped so that their labels don't overlap with the threshold label
+const yTickMinGap = 0.2
+
+// Steps tried for the regular latency ticks of a zoomed in axis, from the
+// largest, so that a narrow range still gets some ticks
+var yTickZoomSteps = []int{yTickStep, 20, 10, 5, 2, 1}
+
+// Least number of regular ticks of a zoomed in axis
+const yTickZoomMinTicks = 4
 
-func (customYTicks) Ticks(min, max float64) []plot.Tick {
+type customYTicks struct {
+	Threshold float64
+	// Whether the axis doesn't start at zero
+	Zoomed bool
+}
+
+func (t customYTicks) Ticks(min, max float64) []plot.Tick {
+	step := yTickStep
+	if t.Zoomed {
+		for _, step = range yTickZoomSteps {
+			if (max-min)/float64(step) >= yTickZoomMinTicks {
+				break
+			}
+		}
+	}
 	ticks := make([]plot.Tick, 0)
-	for i := 0; float64(i) <= max; i += 50 {
+	for i := int(math.Ceil(min/float64(step))) * step; float64(i) <= max; i += step {
+		if math.Abs(float64(i)-t.Threshold) < float64(step)*yTickMinGap {
+			continue
+		}
 		ticks = append(
 			ticks,
 			plot.Tick{
@@ -749,12 +2308,15 @@ func (customYTicks) Ticks(min, max float64) []plot.Tick {
 			},
 		)
 	}
-	ticks = append(
-		ticks,
-		plot.Tick{
-			Value: float64(30),
-			Label: "Real-Time -- " + strconv.Itoa(30) + "ms",
-		},
-	)
+	// Ticks outside of the axis would be drawn beyond it
+	if t.Threshold >= min && t.Threshold <= max {
+		ticks = append(
+			ticks,
+			plot.Tick{
+				Value: t.Threshold,
+				Label: "Real-Time -- " + strconv.FormatFloat(t.Threshold, 'f', -1, 64) + "ms",
+			},
+		)
+	}
 	return ticks
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Maximum number of response (and request) body bytes kept in a failure sample
+const sampleBodySize = 1024
+
+// Headers whose values are credentials, redacted from failure samples since
+// the reports get shared. Headers ending in -Token or -Key are redacted too
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+type failureSample struct {
+	Request  sampleRequest  `json:"request" yaml:"request"`
+	Response sampleResponse `json:"response" yaml:"response"`
+}
+
+type sampleRequest struct {
+	Method string      `json:"method" yaml:"method"`
+	URL    string      `json:"url" yaml:"url"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+type sampleResponse struct {
+	Code   uint16      `json:"code" yaml:"code"`
+	Error  string      `json:"error" yaml:"error"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   string      `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Build a failure sample from a failed result. Results don't carry the request
+// itself, so it's reconstructed from the endpoint's target
+func newFailureSample(target endpointTarget, response *vegeta.Result) failureSample {
+	body := target.Body
+	if target.BodyTemplate != "" {
+		body = "(rendered from " + target.BodyTemplate + ")"
+	}
+	method := response.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return failureSample{
+		Request: sampleRequest{
+			Method: method,
+			URL:    response.URL,
+			Header: redactHeader(target.Header),
+			Body:   truncate(body, sampleBodySize),
+		},
+		Response: sampleResponse{
+			Code:   response.Code,
+			Error:  response.Error,
+			Header: redactHeader(response.Headers),
+			Body:   truncate(string(response.Body), sampleBodySize),
+		},
+	}
+}
+
+// Copy the headers with the values of the sensitive ones replaced
+func redactHeader(header http.Header) http.Header {
+	if header == nil {
+		return nil
+	}
+	redacted := make(http.Header, len(header))
+	for key, values := range header {
+		if isSensitiveHeader(key) {
+			values = []string{"(redacted)"}
+		}
+		redacted[key] = values
+	}
+	return redacted
+}
+
+func isSensitiveHeader(key string) bool {
+	canonical := http.CanonicalHeaderKey(key)
+	return sensitiveHeaders[canonical] || strings.HasSuffix(canonical, "-Token") || strings.HasSuffix(canonical, "-Key")
+}
+
+// Copy an endpoint with the sensitive values of its request and response
+// headers redacted, for the exports indexing it in a shared service
+func redactEndpoint(endpoint endpointDetails) endpointDetails {
+	endpoint.Target.Header = redactHeader(endpoint.Target.Header)
+	if endpoint.Target.HeaderList != nil {
+		list := make([]headerField, len(endpoint.Target.HeaderList))
+		for i, field := range endpoint.Target.HeaderList {
+			if isSensitiveHeader(field.Name) {
+				field.Value = "(redacted)"
+			}
+			list[i] = field
+		}
+		endpoint.Target.HeaderList = list
+	}
+	endpoint.ResponseHeaders = redactHeader(endpoint.ResponseHeaders)
+	return endpoint
+}
+
+func truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	return value[:size] + "... (truncated)"
+}
+
+func printFailureSamples(samples []failureSample) {
+	os.Stdout.Write([]byte("Failure samples:\n"))
+	for i, sample := range samples {
+		os.Stdout.Write([]byte("  #" + strconv.Itoa(i+1) + " " + sample.Request.Method + " " + sample.Request.URL +
+			" -> " + sample.Response.Error + "\n"))
+		for _, key := range sortedHeaderKeys(sample.Response.Header) {
+			os.Stdout.Write([]byte("     " + key + ": " + strings.Join(sample.Response.Header[key], ", ") + "\n"))
+		}
+		if sample.Response.Body != "" {
+			os.Stdout.Write([]byte("     " + strings.ReplaceAll(sample.Response.Body, "\n", "\n     ") + "\n"))
+		}
+	}
+}
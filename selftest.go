@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Query an in-process server answering after a fixed latency, and compare
+// the measured percentiles with it to validate the measurement pipeline
+func runSelfTest(latency time.Duration, rate int, duration string, tolerance float64) error {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(latency)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	endpoint := endpointDetails{
+		Name:   "self-test",
+		Target: endpointTarget{URL: server.URL, Method: "GET"},
+		Query:  defaultQuery(),
+	}
+	endpoint.Query.RequestRate = rate
+	endpoint.Query.Duration = duration
+	queryAPI(&endpoint, queryOptions{})
+
+	metrics := endpoint.Metrics
+	injected := durationToMs(latency)
+	os.Stdout.Write([]byte("Self-test: " + strconv.Itoa(rate) + " req/s for " + duration +
+		" against an in-process server answering after " + formatMs(injected) + "\n"))
+	os.Stdout.Write([]byte("Requests: " + strconv.FormatUint(metrics.Requests, 10) +
+		", success: " + formatPercent(metrics.Success) + "\n"))
+	percentiles := []struct {
+		Name    string
+		Latency time.Duration
+	}{
+		{"50%", metrics.Latencies.P50},
+		{"90%", metrics.Latencies.P90},
+		{"95%", metrics.Latencies.P95},
+		{"99%", metrics.Latencies.P99},
+		{"Max", metrics.Latencies.Max},
+	}
+	for _, percentile := range percentiles {
+		measured := durationToMs(percentile.Latency)
+		os.Stdout.Write([]byte(percentile.Name + "\t" + formatMs(measured) + "\toverhead " + formatMs(measured-injected) + "\n"))
+	}
+
+	overhead := durationToMs(metrics.Latencies.P99) - injected
+	if metrics.Success < 1 || overhead < 0 || overhead > tolerance {
+		return exitWith(exitReasonSelfTestFailure, nil, "Self-test failed: the overhead at 99% is "+formatMs(overhead)+
+			" with "+formatPercent(metrics.Success)+" success, expected at most "+formatMs(tolerance)+" with 100% success")
+	}
+	os.Stdout.Write([]byte("Self-test passed: the overhead at 99% is " + formatMs(overhead) + ", within the " + formatMs(tolerance) + " tolerance\n"))
+	return nil
+}
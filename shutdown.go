@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// Endpoints whose results an export did and didn't deliver
+type exportOutcome struct {
+	Export      string
+	Delivered   []string
+	Undelivered []string
+}
+
+// Tell which endpoints an export delivered from the error it returned. Splunk
+// events and Elasticsearch documents are delivered per endpoint, the other
+// exports deliver all of the endpoints or none of them
+func newExportOutcome(export string, endpoints []endpointDetails, err error) exportOutcome {
+	outcome := exportOutcome{Export: export}
+	failed := make(map[int]bool)
+	var partial sendErrors
+	if errors.As(err, &partial) {
+		for _, i := range partial.Failed {
+			failed[i] = true
+		}
+	}
+	for i := range endpoints {
+		if err == nil || partial.Total > 0 && !failed[i] {
+			outcome.Delivered = append(outcome.Delivered, endpointName(endpoints[i]))
+		} else {
+			outcome.Undelivered = append(outcome.Undelivered, endpointName(endpoints[i]))
+		}
+	}
+	return outcome
+}
+
+// Cancel the exports in flight on the first SIGINT or SIGTERM, so they're
+// abandoned cleanly and what was delivered can be reported, rather than
+// killing rtapi halfway through a request. Once interrupted, the signals get
+// their default behavior back, so a second one exits immediately. The
+// returned function stops listening and tells whether rtapi was interrupted
+func interruptExports(cancel context.CancelFunc) func() bool {
+	var interrupted int32
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			atomic.StoreInt32(&interrupted, 1)
+			signal.Stop(signals)
+			os.Stderr.Write([]byte("Interrupted, abandoning the exports in flight (interrupt again to exit immediately)\n"))
+			cancel()
+		case <-done:
+		}
+	}()
+	return func() bool {
+		signal.Stop(signals)
+		close(done)
+		return atomic.LoadInt32(&interrupted) == 1
+	}
+}
+
+func printExportOutcomes(outcomes []exportOutcome) {
+	os.Stderr.Write([]byte("Exports when interrupted:\n"))
+	for _, outcome := range outcomes {
+		line := "  " + outcome.Export + ": "
+		switch {
+		case len(outcome.Undelivered) == 0:
+			line += "delivered"
+		case len(outcome.Delivered) == 0:
+			line += "not delivered"
+		default:
+			line += "delivered " + strings.Join(outcome.Delivered, ", ") + "; not delivered " + strings.Join(outcome.Undelivered, ", ")
+		}
+		os.Stderr.Write([]byte(line + "\n"))
+	}
+}
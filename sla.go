@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A target of the form "Percent% of requests complete under Latency"
+type slaTarget struct {
+	Percent float64
+	Latency time.Duration
+}
+
+type slaResult struct {
+	Latency time.Duration `json:"latency" yaml:"latency"`
+	// Target and achieved share of requests under the latency, as percentages
+	Target   float64 `json:"target" yaml:"target"`
+	Achieved float64 `json:"achieved" yaml:"achieved"`
+	Met      bool    `json:"met" yaml:"met"`
+}
+
+// Parse a comma separated list of SLA targets such as "95:50ms,99:200ms"
+func parseSLA(value string) []slaTarget {
+	var targets []slaTarget
+	for _, field := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(field), ":")
+		if len(parts) != 2 {
+			fatal(exitReasonInvalidInput, "Invalid SLA target, expected percent:latency: "+field)
+		}
+		percent, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			fatal(exitReasonInvalidInput, "Invalid SLA percentage: "+parts[0])
+		}
+		latency, err := time.ParseDuration(parts[1])
+		if err != nil || latency <= 0 {
+			fatal(exitReasonInvalidInput, "Invalid SLA latency: "+parts[1])
+		}
+		targets = append(targets, slaTarget{Percent: percent, Latency: latency})
+	}
+	return targets
+}
+
+// Compare the number of requests which completed successfully under each
+// target's latency against the total number of requests
+func slaResults(targets []slaTarget, under []uint64, requests uint64) []slaResult {
+	results := make([]slaResult, len(targets))
+	for i, target := range targets {
+		var achieved float64
+		if requests > 0 {
+			achieved = float64(under[i]) / float64(requests) * 100
+		}
+		results[i] = slaResult{
+			Latency:  target.Latency,
+			Target:   target.Percent,
+			Achieved: achieved,
+			Met:      requests > 0 && achieved >= target.Percent,
+		}
+	}
+	return results
+}
+
+// Describe an SLA result, e.g. "SLA 95% under 50ms: 97.5% (met)"
+func (result slaResult) String() string {
+	verdict := "met"
+	if !result.Met {
+		verdict = "missed"
+	}
+	return "SLA " + strconv.FormatFloat(result.Target, 'f', -1, 64) + "% under " + result.Latency.String() +
+		": " + formatPercent(result.Achieved/100) + " (" + verdict + ")"
+}
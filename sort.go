@@ -0,0 +1,31 @@
+package main
+
+import (
+	"sort"
+)
+
+// Orders the endpoints can be reported in
+var endpointOrders = map[string]func(a, b endpointDetails) bool{
+	// Worst first, so the problem endpoints lead large reports
+	"p99": func(a, b endpointDetails) bool {
+		return a.Metrics.Latencies.P99 > b.Metrics.Latencies.P99
+	},
+	"success": func(a, b endpointDetails) bool {
+		return a.Metrics.Success < b.Metrics.Success
+	},
+	"name": func(a, b endpointDetails) bool {
+		return endpointName(a) < endpointName(b)
+	},
+}
+
+// Sort the endpoints for every report, keeping the config order of the
+// endpoints which compare equal, or all of them for the "config" order
+func sortEndpoints(endpoints []endpointDetails, order string) {
+	less, ok := endpointOrders[order]
+	if !ok {
+		return
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return less(endpoints[i], endpoints[j])
+	})
+}
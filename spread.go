@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Coefficient of variation from which an endpoint's latency is reported as
+// inconsistent, its standard deviation exceeding its mean
+const highLatencyCV = 1.0
+
+// Running standard deviation of the latency, with Welford's algorithm, as
+// vegeta's metrics don't keep it
+type latencySpread struct {
+	count uint64
+	mean  float64
+	m2    float64
+}
+
+func (s *latencySpread) Add(response *vegeta.Result) {
+	s.count++
+	latency := float64(response.Latency)
+	delta := latency - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (latency - s.mean)
+}
+
+// Standard deviation of the latency and its coefficient of variation, the
+// standard deviation relative to the mean
+func (s *latencySpread) Result() (time.Duration, float64) {
+	if s.count < 2 {
+		return 0, 0
+	}
+	stdDev := math.Sqrt(s.m2 / float64(s.count))
+	return time.Duration(stdDev), stdDev / s.mean
+}
+
+func latencySpreadSummary(endpoint endpointDetails) string {
+	summary := "latency standard deviation " + formatMs(durationToMs(endpoint.LatencyStdDev)) +
+		", coefficient of variation " + strconv.FormatFloat(endpoint.LatencyCV, 'f', 2, 64)
+	if endpoint.LatencyCV > highLatencyCV {
+		summary += " (inconsistent)"
+	}
+	return summary
+}
+
+func printLatencySpread(endpoint endpointDetails) {
+	os.Stdout.Write([]byte("Latency spread: " + latencySpreadSummary(endpoint) + "\n"))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Count the responses of an endpoint whose status code isn't the expected one,
+// including requests which failed without a response
+func countStatusMismatches(endpoint endpointDetails) uint64 {
+	expected := uint64(endpoint.Metrics.StatusCodes[strconv.Itoa(endpoint.ExpectStatus)])
+	return endpoint.Metrics.Requests - expected
+}
+
+// Describe the responses of an endpoint which didn't return its expected status code
+func statusMismatchWarning(endpoint endpointDetails) string {
+	expected := strconv.Itoa(endpoint.ExpectStatus)
+	var codes []string
+	for code := range endpoint.Metrics.StatusCodes {
+		if code != expected {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for i, code := range codes {
+		codes[i] = code + " (" + strconv.Itoa(endpoint.Metrics.StatusCodes[code]) + ")"
+	}
+	share := float64(endpoint.StatusMismatches) / float64(endpoint.Metrics.Requests)
+	return "FAILED: " + endpointName(endpoint) + " must return " + expected + " but " +
+		strconv.FormatUint(endpoint.StatusMismatches, 10) + " responses (" + formatPercent(share) +
+		") didn't: " + strings.Join(codes, ", ")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+type statusClassLatency struct {
+	// Class of the status codes, e.g. "2xx"
+	Class     string                `json:"class" yaml:"class"`
+	Requests  uint64                `json:"requests" yaml:"requests"`
+	Latencies vegeta.LatencyMetrics `json:"latencies" yaml:"latencies"`
+}
+
+// Aggregate the latency of responses per status class, as fast failures
+// otherwise mask slow successes in the blended percentiles. Requests which
+// got no response have no status class and are left out
+type statusClassRecorder struct {
+	classes map[int]*statusClassLatency
+}
+
+func (r *statusClassRecorder) Add(response *vegeta.Result) {
+	if response.Code < 100 || response.Code > 599 {
+		return
+	}
+	if r.classes == nil {
+		r.classes = make(map[int]*statusClassLatency)
+	}
+	class := int(response.Code) / 100
+	latency, ok := r.classes[class]
+	if !ok {
+		latency = &statusClassLatency{Class: strconv.Itoa(class) + "xx"}
+		r.classes[class] = latency
+	}
+	latency.Requests++
+	latency.Latencies.Add(response.Latency)
+}
+
+func (r *statusClassRecorder) Result() []statusClassLatency {
+	classes := make([]statusClassLatency, 0, len(r.classes))
+	for _, latency := range r.classes {
+		latency.Latencies.Mean = latency.Latencies.Total / time.Duration(latency.Requests)
+		latency.Latencies.P50 = latency.Latencies.Quantile(0.50)
+		latency.Latencies.P90 = latency.Latencies.Quantile(0.90)
+		latency.Latencies.P95 = latency.Latencies.Quantile(0.95)
+		latency.Latencies.P99 = latency.Latencies.Quantile(0.99)
+		classes = append(classes, *latency)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Class < classes[j].Class
+	})
+	return classes
+}
+
+func printStatusClasses(classes []statusClassLatency) {
+	os.Stdout.Write([]byte("Latency by status [class: count, mean, 50, 90, 95, 99, max]\n"))
+	for _, class := range classes {
+		latencies := class.Latencies
+		os.Stdout.Write([]byte("  " + class.Class + ": " + strconv.FormatUint(class.Requests, 10) + ", " +
+			roundDuration(latencies.Mean).String() + ", " + roundDuration(latencies.P50).String() + ", " +
+			roundDuration(latencies.P90).String() + ", " + roundDuration(latencies.P95).String() + ", " +
+			roundDuration(latencies.P99).String() + ", " + roundDuration(latencies.Max).String() + "\n"))
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+type sweepResult struct {
+	Connections int
+	Rate        float64
+	P99         time.Duration
+}
+
+// Parse a comma separated list of connection counts, e.g. "1,5,10,50,100"
+func parseConnSweep(value string) []int {
+	var counts []int
+	for _, field := range strings.Split(value, ",") {
+		count, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || count <= 0 {
+			fatal(exitReasonInvalidInput, "Invalid connection count in sweep: "+field)
+		}
+		counts = append(counts, count)
+	}
+	return counts
+}
+
+// Query each endpoint once per connection count, keeping every other query
+// parameter as configured
+func runConnSweep(endpoints []endpointDetails, counts []int, options queryOptions) [][]sweepResult {
+	results := make([][]sweepResult, len(endpoints))
+	for i := range endpoints {
+		for _, count := range counts {
+			endpoint := endpoints[i]
+			endpoint.Query.Connections = count
+			queryAPI(&endpoint, options)
+			results[i] = append(results[i], sweepResult{
+				Connections: count,
+				Rate:        endpoint.Metrics.Rate,
+				P99:         endpoint.Metrics.Latencies.P99,
+			})
+		}
+	}
+	return results
+}
+
+func printConnSweep(endpoints []endpointDetails, results [][]sweepResult) {
+	for i := range endpoints {
+		os.Stdout.Write([]byte("------------------------------------\n"))
+		os.Stdout.Write([]byte("API Endpoint: " + endpointName(endpoints[i]) + "\n"))
+		os.Stdout.Write([]byte("------------------------------------\n"))
+		os.Stdout.Write([]byte("Connections\tRate (req/s)\tP99 (ms)\n"))
+		for _, result := range results[i] {
+			os.Stdout.Write([]byte(strconv.Itoa(result.Connections) + "\t" +
+				strconv.FormatFloat(result.Rate, 'f', 2, 64) + "\t" +
+				strconv.FormatFloat(durationToMs(result.P99), 'f', 3, 64) + "\n"))
+		}
+		os.Stdout.Write([]byte("------------------------------------\n\n"))
+	}
+}
+
+// Chart the achieved rate and the latency at 99% of each endpoint against
+// the number of connections, one above the other
+func createConnSweepGraph(endpoints []endpointDetails, results [][]sweepResult) *bytes.Buffer {
+	ratePlot, err := plot.New()
+	if err != nil {
+		panic(err)
+	}
+	ratePlot.Title.Text = "Achieved rate vs connections"
+	ratePlot.X.Label.Text = "Connections"
+	ratePlot.Y.Label.Text = "Rate (req/s)"
+	ratePlot.Y.Min = 0
+	ratePlot.Add(plotter.NewGrid())
+
+	latencyPlot, err := plot.New()
+	if err != nil {
+		panic(err)
+	}
+	latencyPlot.Title.Text = "Latency at 99% vs connections"
+	latencyPlot.X.Label.Text = "Connections"
+	latencyPlot.Y.Label.Text = "Latency (ms)"
+	latencyPlot.Y.Min = 0
+	latencyPlot.Add(plotter.NewGrid())
+
+	for i := range endpoints {
+		rates := make(plotter.XYs, len(results[i]))
+		latencies := make(plotter.XYs, len(results[i]))
+		for j, result := range results[i] {
+			rates[j].X = float64(result.Connections)
+			rates[j].Y = result.Rate
+			latencies[j].X = float64(result.Connections)
+			latencies[j].Y = durationToMs(result.P99)
+		}
+		for _, sweep := range []struct {
+			plot *plot.Plot
+			xys  plotter.XYs
+		}{{ratePlot, rates}, {latencyPlot, latencies}} {
+			lpLine, lpPoints, err := plotter.NewLinePoints(sweep.xys)
+			if err != nil {
+				panic(err)
+			}
+			lpLine.Color = plotutil.Color(i + 1)
+			lpPoints.Color = plotutil.Color(i + 1)
+			lpPoints.Shape = plotutil.Shape(i + 1)
+			sweep.plot.Add(lpLine, lpPoints)
+			sweep.plot.Legend.Add(endpointName(endpoints[i]), lpLine, lpPoints)
+		}
+	}
+
+	img := vgimg.New(25*vg.Centimeter, 25*vg.Centimeter)
+	dc := draw.New(img)
+	tiles := draw.Tiles{
+		Rows:      2,
+		Cols:      1,
+		PadTop:    vg.Centimeter,
+		PadBottom: vg.Centimeter,
+		PadLeft:   vg.Centimeter,
+		PadRight:  vg.Centimeter,
+		PadY:      vg.Centimeter,
+	}
+	canvases := plot.Align([][]*plot.Plot{{ratePlot}, {latencyPlot}}, tiles, dc)
+	ratePlot.Draw(canvases[0][0])
+	latencyPlot.Draw(canvases[1][0])
+
+	buffer := new(bytes.Buffer)
+	_, err = vgimg.PngCanvas{Canvas: img}.WriteTo(buffer)
+	if err != nil {
+		panic(err)
+	}
+	return buffer
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+type latencyTableColumn struct {
+	Title string
+	Width float64
+}
+
+// Width in mm of an A4 page between the report margins
+const latencyTableWidth = 159.2
+
+// Columns of the latency table: the endpoint, its requests and success, then
+// its latency at each percentile and its maximum sharing the remaining width
+func latencyTableColumns(percentiles []float64) []latencyTableColumn {
+	columns := []latencyTableColumn{{"Endpoint", 47.2}, {"Requests", 16}, {"Success", 16}}
+	width := (latencyTableWidth - 47.2 - 2*16) / float64(len(percentiles)+1)
+	for _, percentile := range percentiles {
+		columns = append(columns, latencyTableColumn{formatPercentile(percentile) + "%", width})
+	}
+	return append(columns, latencyTableColumn{"Max", width})
+}
+
+// Write a table of the latency of every endpoint at the given percentiles,
+// used in place of the graph
+func writeLatencyTable(pdf *gofpdf.Fpdf, endpoints []endpointDetails, lineHt float64, percentiles []float64) {
+	columns := latencyTableColumns(percentiles)
+	// Narrower columns than the default ones need a smaller font to fit
+	fontSize := 9.0
+	if columns[len(columns)-1].Width < 16 {
+		fontSize = 7.5
+	}
+	pdf.SetFont("ArialTrue", "B", fontSize)
+	for _, column := range columns {
+		pdf.CellFormat(column.Width, lineHt, column.Title, "B", 0, "C", false, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetFont("ArialTrue", "", fontSize)
+	for i := range endpoints {
+		metrics := endpoints[i].Metrics
+		cells := []string{
+			fitText(pdf, endpointName(endpoints[i]), columns[0].Width-2),
+			strconv.FormatUint(metrics.Requests, 10),
+			formatPercent(metrics.Success),
+		}
+		for _, percentile := range percentiles {
+			cells = append(cells, formatMs(durationToMs(latencyAtPercentile(metrics, percentile))))
+		}
+		cells = append(cells, formatMs(durationToMs(metrics.Latencies.Max)))
+		for j, cell := range cells {
+			align := "R"
+			if j == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(columns[j].Width, lineHt, cell, "", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.SetFont("ArialTrue", "", 10)
+}
+
+// Shorten the text with an ellipsis until it fits in the given width
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
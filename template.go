@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	mathrand "math/rand"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+func init() {
+	mathrand.Seed(time.Now().UnixNano())
+}
+
+// Functions available to body templates
+var templateFuncs = template.FuncMap{
+	"uuid":    newUUID,
+	"randInt": randInt,
+	"now":     time.Now,
+}
+
+// Template functions whose output changes on every call, forcing the body
+// to be rendered again for each request
+var dynamicTemplateFuncs = map[string]bool{
+	"uuid":    true,
+	"randInt": true,
+	"now":     true,
+}
+
+// Build the targeter for an endpoint. Bodies rendered from a template using
+// any dynamic function are rendered again for every request, otherwise the
+// body is rendered once before the attack starts
+func newTargeter(target endpointTarget) vegeta.Targeter {
+	if target.BodySize != "" {
+		return vegeta.NewStaticTargeter(
+			vegeta.Target{
+				URL:    target.URL,
+				Method: target.Method,
+				Body:   generateBody(target.BodySize, target.BodyFill),
+				Header: target.Header,
+			},
+		)
+	}
+	if target.BodyTemplate == "" {
+		return vegeta.NewStaticTargeter(
+			vegeta.Target{
+				URL:    target.URL,
+				Method: target.Method,
+				Body:   []byte(target.Body),
+				Header: target.Header,
+			},
+		)
+	}
+
+	tmpl := parseBodyTemplate(target.BodyTemplate)
+	if !isDynamicTemplate(tmpl.Tree.Root) {
+		return vegeta.NewStaticTargeter(
+			vegeta.Target{
+				URL:    target.URL,
+				Method: target.Method,
+				Body:   renderBodyTemplate(tmpl, target.TemplateData),
+				Header: target.Header,
+			},
+		)
+	}
+	return func(tgt *vegeta.Target) error {
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, target.TemplateData); err != nil {
+			return err
+		}
+		tgt.URL = target.URL
+		tgt.Method = target.Method
+		tgt.Body = buffer.Bytes()
+		tgt.Header = target.Header
+		return nil
+	}
+}
+
+func parseBodyTemplate(file string) *template.Template {
+	tmpl, err := loadBodyTemplate(file)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	return tmpl
+}
+
+func loadBodyTemplate(file string) (*template.Template, error) {
+	byteValue, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(file).Funcs(templateFuncs).Parse(string(byteValue))
+}
+
+func renderBodyTemplate(tmpl *template.Template, data map[string]interface{}) []byte {
+	var buffer bytes.Buffer
+	err := tmpl.Execute(&buffer, data)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	return buffer.Bytes()
+}
+
+// Walk the template parse tree looking for calls to dynamic functions
+func isDynamicTemplate(node parse.Node) bool {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			if isDynamicTemplate(child) {
+				return true
+			}
+		}
+	case *parse.ActionNode:
+		return isDynamicTemplate(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if isDynamicTemplate(cmd) {
+				return true
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if isDynamicTemplate(arg) {
+				return true
+			}
+		}
+	case *parse.IdentifierNode:
+		return dynamicTemplateFuncs[n.Ident]
+	case *parse.IfNode:
+		return isDynamicTemplate(n.Pipe) || isDynamicTemplate(n.List) || isDynamicTemplate(n.ElseList)
+	case *parse.RangeNode:
+		return isDynamicTemplate(n.Pipe) || isDynamicTemplate(n.List) || isDynamicTemplate(n.ElseList)
+	case *parse.WithNode:
+		return isDynamicTemplate(n.Pipe) || isDynamicTemplate(n.List) || isDynamicTemplate(n.ElseList)
+	case *parse.TemplateNode:
+		// Nested templates may call anything, so play it safe
+		return true
+	}
+	return false
+}
+
+// Generate a random (version 4) UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Generate a random integer in the range [min, max)
+func randInt(min int, max int) int {
+	if max <= min {
+		return min
+	}
+	return min + mathrand.Intn(max-min)
+}
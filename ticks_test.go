@@ -0,0 +1,38 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCustomYTicksThreshold(t *testing.T) {
+	tests := []struct {
+		threshold float64
+		max       float64
+	}{
+		{threshold: 25, max: 300},
+		{threshold: 30, max: 300},
+		{threshold: 50, max: 300},
+		{threshold: 100, max: 300},
+	}
+	for _, test := range tests {
+		ticks := customYTicks{Threshold: test.threshold}.Ticks(0, test.max)
+		var thresholdLabels int
+		for _, tick := range ticks {
+			if strings.HasPrefix(tick.Label, "Real-Time") {
+				thresholdLabels++
+				if tick.Value != test.threshold {
+					t.Errorf("threshold %v: threshold label at %v", test.threshold, tick.Value)
+				}
+				continue
+			}
+			if tick.Label != "" && math.Abs(tick.Value-test.threshold) < yTickStep*yTickMinGap {
+				t.Errorf("threshold %v: label %q at %v is too close to the threshold", test.threshold, tick.Label, tick.Value)
+			}
+		}
+		if thresholdLabels != 1 {
+			t.Errorf("threshold %v: got %d threshold labels, want 1", test.threshold, thresholdLabels)
+		}
+	}
+}
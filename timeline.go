@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// Length of the time buckets results are aggregated in
+const timelineInterval = time.Second
+
+type timelineBucket struct {
+	// Time since the start of the endpoint's attack
+	Offset   time.Duration `json:"offset" yaml:"offset"`
+	Requests uint64        `json:"requests" yaml:"requests"`
+	Errors   uint64        `json:"errors" yaml:"errors"`
+	// Mean latency of the requests in the bucket
+	Latency time.Duration `json:"latency" yaml:"latency"`
+}
+
+// Aggregate results in consecutive time buckets from the start of the attack
+type timelineRecorder struct {
+	start   time.Time
+	buckets []timelineBucket
+	total   []time.Duration
+}
+
+func (t *timelineRecorder) Add(response *vegeta.Result) {
+	if t.start.IsZero() {
+		t.start = response.Timestamp
+	}
+	index := int(response.Timestamp.Sub(t.start) / timelineInterval)
+	if index < 0 {
+		index = 0
+	}
+	for len(t.buckets) <= index {
+		t.buckets = append(t.buckets, timelineBucket{Offset: time.Duration(len(t.buckets)) * timelineInterval})
+		t.total = append(t.total, 0)
+	}
+	t.buckets[index].Requests++
+	if response.Error != "" {
+		t.buckets[index].Errors++
+	}
+	t.total[index] += response.Latency
+}
+
+func (t *timelineRecorder) Result() []timelineBucket {
+	for i := range t.buckets {
+		if t.buckets[i].Requests > 0 {
+			t.buckets[i].Latency = t.total[i] / time.Duration(t.buckets[i].Requests)
+		}
+	}
+	return t.buckets
+}
+
+// Plot the mean latency of each endpoint over the time of its attack, shading
+// the seconds in which its share of errors exceeded the threshold (a ratio)
+// and marking the given events
+func createTimelineGraph(endpoints []endpointDetails, errorThreshold float64, events []graphEvent) *bytes.Buffer {
+	p, err := plot.New()
+	if err != nil {
+		panic(err)
+	}
+	p.Title.Text = "Mean latency over time, shaded where errors exceed " + formatPercent(errorThreshold)
+	p.X.Label.Text = "Time (s)"
+	p.Y.Label.Text = "Latency (ms)"
+	p.Y.Min = 0
+	p.Add(plotter.NewGrid())
+
+	var maxLatency float64
+	for i := range endpoints {
+		for _, bucket := range endpoints[i].Timeline {
+			if latency := durationToMs(bucket.Latency); latency > maxLatency {
+				maxLatency = latency
+			}
+		}
+	}
+	bandTop := maxLatency * 1.05
+	if bandTop == 0 {
+		bandTop = 1
+	}
+
+	for i := range endpoints {
+		timeline := endpoints[i].Timeline
+		r, g, b, _ := plotutil.Color(i + 1).RGBA()
+		shade := color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 48}
+		var bands []plot.Plotter
+		for _, bucket := range timeline {
+			if bucket.Requests == 0 || float64(bucket.Errors)/float64(bucket.Requests) <= errorThreshold {
+				continue
+			}
+			start := bucket.Offset.Seconds()
+			end := start + timelineInterval.Seconds()
+			band, err := plotter.NewPolygon(plotter.XYs{{X: start, Y: 0}, {X: end, Y: 0}, {X: end, Y: bandTop}, {X: start, Y: bandTop}})
+			if err != nil {
+				panic(err)
+			}
+			band.Color = shade
+			band.LineStyle.Width = 0
+			bands = append(bands, band)
+		}
+		// Draw the bands first so the latency lines stay on top of them
+		p.Add(bands...)
+		if len(bands) > 0 {
+			p.Legend.Add(endpointName(endpoints[i])+" errors", bands[0].(*plotter.Polygon))
+		}
+
+		latencies := make(plotter.XYs, len(timeline))
+		for j, bucket := range timeline {
+			latencies[j].X = bucket.Offset.Seconds() + timelineInterval.Seconds()/2
+			latencies[j].Y = durationToMs(bucket.Latency)
+		}
+		lpLine, lpPoints, err := plotter.NewLinePoints(latencies)
+		if err != nil {
+			panic(err)
+		}
+		lpLine.Color = plotutil.Color(i + 1)
+		lpPoints.Color = plotutil.Color(i + 1)
+		lpPoints.Shape = plotutil.Shape(i + 1)
+		p.Add(lpLine, lpPoints)
+		p.Legend.Add(endpointName(endpoints[i]), lpLine, lpPoints)
+	}
+	addEventMarkers(p, events, bandTop)
+	p.Legend.Top = true
+
+	writer, err := p.WriterTo(6*vg.Inch, 4*vg.Inch, "png")
+	if err != nil {
+		panic(err)
+	}
+	buffer := new(bytes.Buffer)
+	writer.WriteTo(buffer)
+	return buffer
+}
+
+// Achieved and requested rates of an endpoint during a second of its attack
+type ratePoint struct {
+	Offset    time.Duration
+	Achieved  float64
+	Requested float64
+}
+
+// Rate at which requests were sent during each second of the attack, next to
+// the rate the pacer was meant to send them at, or 0 if it isn't paced. The
+// last second is left out as the attack usually ends partway through it
+func rateTimeline(endpoint endpointDetails) []ratePoint {
+	if len(endpoint.Timeline) < 2 {
+		return nil
+	}
+	var pacer vegeta.Pacer = vegeta.Rate{}
+	if !endpoint.Query.WorkerRamp {
+		pacer = newPacer(endpoint.Query)
+	}
+	buckets := endpoint.Timeline[:len(endpoint.Timeline)-1]
+	points := make([]ratePoint, len(buckets))
+	for i, bucket := range buckets {
+		points[i] = ratePoint{
+			Offset:    bucket.Offset,
+			Achieved:  float64(bucket.Requests) / timelineInterval.Seconds(),
+			Requested: pacer.Rate(bucket.Offset + timelineInterval/2),
+		}
+	}
+	return points
+}
+
+// Share of the requested rate below which a second counts as a dip
+const rateDipRatio = 0.9
+
+// Summarize the achieved rate over time: its lowest second and the seconds
+// in which it fell short of the requested rate
+func printRateTimeline(endpoint endpointDetails) {
+	points := rateTimeline(endpoint)
+	if len(points) == 0 {
+		return
+	}
+	lowest := points[0]
+	var dips []string
+	for _, point := range points {
+		if point.Achieved < lowest.Achieved {
+			lowest = point
+		}
+		if point.Requested > 0 && point.Achieved < point.Requested*rateDipRatio {
+			dips = append(dips, strconv.Itoa(int(point.Offset.Seconds()))+"s ("+formatRate(point.Achieved)+" of "+formatRate(point.Requested)+")")
+		}
+	}
+	os.Stdout.Write([]byte("Achieved rate over time: lowest " + formatRate(lowest.Achieved) + " at " +
+		strconv.Itoa(int(lowest.Offset.Seconds())) + "s, " + strconv.Itoa(len(dips)) + " of " + strconv.Itoa(len(points)) +
+		" seconds below " + formatPercent(rateDipRatio) + " of the requested rate\n"))
+	if len(dips) > 0 {
+		os.Stdout.Write([]byte("  Dips: " + strings.Join(dips, ", ") + "\n"))
+	}
+}
+
+// Plot the rate each endpoint's requests were sent at over the time of its
+// attack, with the requested rate dashed, so stalls of the load generator
+// stand out, and mark the given events
+func createRateTimelineGraph(endpoints []endpointDetails, events []graphEvent) *bytes.Buffer {
+	p, err := plot.New()
+	if err != nil {
+		panic(err)
+	}
+	p.Title.Text = "Achieved rate over time, requested rate dashed"
+	p.X.Label.Text = "Time (s)"
+	p.Y.Label.Text = "Rate (req/s)"
+	p.Y.Min = 0
+	p.Add(plotter.NewGrid())
+
+	var top float64
+	for i := range endpoints {
+		points := rateTimeline(endpoints[i])
+		if len(points) == 0 {
+			continue
+		}
+		achieved := make(plotter.XYs, len(points))
+		requested := make(plotter.XYs, 0, len(points))
+		for j, point := range points {
+			x := point.Offset.Seconds() + timelineInterval.Seconds()/2
+			achieved[j] = plotter.XY{X: x, Y: point.Achieved}
+			top = math.Max(top, point.Achieved)
+			if !endpoints[i].Query.WorkerRamp {
+				requested = append(requested, plotter.XY{X: x, Y: point.Requested})
+				top = math.Max(top, point.Requested)
+			}
+		}
+		lpLine, lpPoints, err := plotter.NewLinePoints(achieved)
+		if err != nil {
+			panic(err)
+		}
+		lpLine.Color = plotutil.Color(i + 1)
+		lpPoints.Color = plotutil.Color(i + 1)
+		lpPoints.Shape = plotutil.Shape(i + 1)
+		p.Add(lpLine, lpPoints)
+		p.Legend.Add(endpointName(endpoints[i]), lpLine, lpPoints)
+		if len(requested) > 0 {
+			line, err := plotter.NewLine(requested)
+			if err != nil {
+				panic(err)
+			}
+			line.Color = plotutil.Color(i + 1)
+			line.Dashes = []vg.Length{vg.Points(4)}
+			p.Add(line)
+		}
+	}
+	addEventMarkers(p, events, top)
+	p.Legend.Top = true
+
+	writer, err := p.WriterTo(6*vg.Inch, 4*vg.Inch, "png")
+	if err != nil {
+		panic(err)
+	}
+	buffer := new(bytes.Buffer)
+	writer.WriteTo(buffer)
+	return buffer
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// How long to wait for a "100 Continue" response before sending the body anyway
+const expectContinueTimeout = time.Second
+
+// Build an HTTP client equivalent to the one vegeta uses by default, with the
+// transport settings vegeta doesn't expose options for, or only by replacing
+// the dialer. It must be passed to
+// the attacker before any other option that tweaks the transport
+func newHTTPClient(query endpointQuery) *http.Client {
+	dialer := &net.Dialer{
+		LocalAddr: &net.TCPAddr{IP: vegeta.DefaultLocalAddr.IP, Zone: vegeta.DefaultLocalAddr.Zone},
+		KeepAlive: 30 * time.Second,
+	}
+	if len(query.DNSServers) > 0 {
+		dialer.Resolver = newResolver(query.DNSServers)
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		Dial:                dialer.Dial,
+		TLSClientConfig:     vegeta.DefaultTLSConfig,
+		MaxIdleConnsPerHost: vegeta.DefaultConnections,
+		MaxConnsPerHost:     vegeta.DefaultMaxConnections,
+	}
+	if query.Expect100Continue {
+		transport.ExpectContinueTimeout = expectContinueTimeout
+	}
+	// Rather than vegeta.KeepAlive(false), which would replace the dialer
+	// and its DNS servers with vegeta's own
+	if query.DisableKeepAlive {
+		transport.DisableKeepAlives = true
+		dialer.KeepAlive = 0
+	}
+	return &http.Client{
+		Timeout:   vegeta.DefaultTimeout,
+		Transport: transport,
+	}
+}
+
+// Build a resolver querying the given DNS servers in turn, so a server
+// which doesn't answer is skipped when the query is retried
+func newResolver(servers []string) *net.Resolver {
+	addresses := make([]string, len(servers))
+	for i, server := range servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		addresses[i] = server
+	}
+	var next uint64
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			address := addresses[(atomic.AddUint64(&next, 1)-1)%uint64(len(addresses))]
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+type trendRun struct {
+	File      string
+	Time      time.Time
+	Endpoints []endpointDetails
+}
+
+// Load the runs saved with --json, in the given order
+func loadTrendRuns(files []string) []trendRun {
+	runs := make([]trendRun, len(files))
+	for i, file := range files {
+		runs[i] = trendRun{File: file, Endpoints: parseEndpointsJSON(file)}
+		for _, endpoint := range runs[i].Endpoints {
+			if runs[i].Time.IsZero() || endpoint.Metrics.Earliest.Before(runs[i].Time) {
+				runs[i].Time = endpoint.Metrics.Earliest
+			}
+		}
+	}
+	return runs
+}
+
+// List the endpoint names found across all runs, in order of first appearance
+func trendEndpointNames(runs []trendRun) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, run := range runs {
+		for _, endpoint := range run.Endpoints {
+			name := endpointName(endpoint)
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func findEndpoint(endpoints []endpointDetails, name string) *endpointDetails {
+	for i := range endpoints {
+		if endpointName(endpoints[i]) == name {
+			return &endpoints[i]
+		}
+	}
+	return nil
+}
+
+func printTrend(runs []trendRun) {
+	for _, name := range trendEndpointNames(runs) {
+		os.Stdout.Write([]byte("------------------------------------\n"))
+		os.Stdout.Write([]byte("API Endpoint: " + name + "\n"))
+		os.Stdout.Write([]byte("------------------------------------\n"))
+		os.Stdout.Write([]byte("Run\tTime\tP99 (ms)\n"))
+		for i, run := range runs {
+			p99 := "-"
+			if endpoint := findEndpoint(run.Endpoints, name); endpoint != nil {
+				p99 = strconv.FormatFloat(durationToMs(endpoint.Metrics.Latencies.P99), 'f', 3, 64)
+			}
+			os.Stdout.Write([]byte(strconv.Itoa(i+1) + "\t" + run.Time.Format(time.RFC3339) + "\t" + p99 + "\n"))
+		}
+		os.Stdout.Write([]byte("------------------------------------\n\n"))
+	}
+}
+
+// Plot the latency at 99% of each endpoint across the runs, skipping the
+// runs an endpoint is missing from
+func createTrendGraph(runs []trendRun, threshold float64) *bytes.Buffer {
+	p, err := plot.New()
+	if err != nil {
+		panic(err)
+	}
+	p.Title.Text = "Latency at 99% across runs"
+	p.X.Label.Text = "Run"
+	p.X.Tick.Marker = trendTicks{runs}
+	p.Y.Label.Text = "Latency (ms)"
+	p.Y.Min = 0
+	p.Y.Tick.Marker = customYTicks{Threshold: threshold}
+	p.Add(plotter.NewGrid())
+
+	for i, name := range trendEndpointNames(runs) {
+		var points plotter.XYs
+		for j, run := range runs {
+			if endpoint := findEndpoint(run.Endpoints, name); endpoint != nil {
+				points = append(points, plotter.XY{X: float64(j + 1), Y: durationToMs(endpoint.Metrics.Latencies.P99)})
+			}
+		}
+		lpLine, lpPoints, err := plotter.NewLinePoints(points)
+		if err != nil {
+			panic(err)
+		}
+		lpLine.Color = plotutil.Color(i + 1)
+		lpLine.Dashes = plotutil.Dashes(i + 1)
+		lpPoints.Color = plotutil.Color(i + 1)
+		lpPoints.Shape = plotutil.Shape(i + 1)
+		p.Add(lpLine, lpPoints)
+		p.Legend.Add(name, lpLine, lpPoints)
+	}
+
+	// Highlight the real-time threshold, which also keeps it within the Y axis range
+	lineThreshold, err := plotter.NewLine(
+		plotter.XYs{
+			plotter.XY{X: 0.5, Y: threshold},
+			plotter.XY{X: float64(len(runs)) + 0.5, Y: threshold},
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+	lineThreshold.LineStyle = draw.LineStyle{
+		Width:    vg.Length(1),
+		Dashes:   []vg.Length{vg.Length(4)},
+		DashOffs: vg.Length(8),
+	}
+	p.Add(lineThreshold)
+	p.X.Min = 0.5
+	p.X.Max = float64(len(runs)) + 0.5
+
+	buffer := new(bytes.Buffer)
+	wrt, err := p.WriterTo(25*vg.Centimeter, 25*vg.Centimeter, "png")
+	if err != nil {
+		panic(err)
+	}
+	wrt.WriteTo(buffer)
+	return buffer
+}
+
+// Label each run on the X axis with its number and the time it started
+type trendTicks struct {
+	runs []trendRun
+}
+
+func (t trendTicks) Ticks(min, max float64) []plot.Tick {
+	ticks := make([]plot.Tick, len(t.runs))
+	for i, run := range t.runs {
+		ticks[i] = plot.Tick{
+			Value: float64(i + 1),
+			Label: "#" + strconv.Itoa(i+1) + "\n" + run.Time.Format("01-02 15:04"),
+		}
+	}
+	return ticks
+}
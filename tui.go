@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Latency buckets the TUI shows when --histogram hasn't been set
+var tuiHistogramBuckets = vegeta.Buckets{
+	0,
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	30 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// The TUI reads single key presses, so it needs a terminal on both ends
+func isInteractiveTerminal() bool {
+	return terminal.IsTerminal(int(os.Stdin.Fd())) && terminal.IsTerminal(int(os.Stdout.Fd()))
+}
+
+type tuiView int
+
+const (
+	tuiHistogramView tuiView = iota
+	tuiStatusView
+)
+
+type tui struct {
+	endpoints []endpointDetails
+	threshold float64
+	selected  int
+	offset    int
+	view      tuiView
+}
+
+// Show the results in an interactive terminal UI until the user quits
+func runTUI(endpoints []endpointDetails, threshold float64) error {
+	fd := int(os.Stdin.Fd())
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer terminal.Restore(fd, state)
+
+	// Switch to the alternate screen and hide the cursor until we're done
+	os.Stdout.Write([]byte("\x1b[?1049h\x1b[?25l"))
+	defer os.Stdout.Write([]byte("\x1b[?25h\x1b[?1049l"))
+
+	ui := tui{endpoints: endpoints, threshold: threshold}
+	key := make([]byte, 3)
+	for {
+		ui.draw()
+		n, err := os.Stdin.Read(key)
+		if err != nil {
+			return err
+		}
+		switch string(key[:n]) {
+		case "q", "\x1b", "\x03":
+			return nil
+		case "k", "\x1b[A":
+			if ui.selected > 0 {
+				ui.selected--
+			}
+		case "j", "\x1b[B":
+			if ui.selected < len(ui.endpoints)-1 {
+				ui.selected++
+			}
+		case "\r", "\t", " ":
+			if ui.view == tuiHistogramView {
+				ui.view = tuiStatusView
+			} else {
+				ui.view = tuiHistogramView
+			}
+		}
+	}
+}
+
+func (ui *tui) draw() {
+	width, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = 80, 24
+	}
+	var lines []string
+	lines = append(lines, "Real-Time API Latency Report — ↑/↓ select, enter switch view, q quit", "")
+
+	// Keep the selected endpoint visible, leaving at least half the screen for its details
+	listHeight := height / 2
+	if listHeight > len(ui.endpoints) {
+		listHeight = len(ui.endpoints)
+	}
+	if ui.selected < ui.offset {
+		ui.offset = ui.selected
+	} else if ui.selected >= ui.offset+listHeight {
+		ui.offset = ui.selected - listHeight + 1
+	}
+	for i := ui.offset; i < ui.offset+listHeight; i++ {
+		endpoint := ui.endpoints[i]
+		p99 := durationToMs(endpoint.Metrics.Latencies.P99)
+		verdict := "PASS"
+		if !endpointPassed(endpoint, ui.threshold) {
+			verdict = "FAIL"
+		}
+		line := fitTUILine(verdict+"  P99 "+formatMs(p99)+"  success "+formatPercent(endpoint.Metrics.Success)+
+			"  "+endpointName(endpoint), width)
+		if i == ui.selected {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "")
+
+	endpoint := ui.endpoints[ui.selected]
+	if ui.view == tuiHistogramView {
+		lines = append(lines, histogramLines(endpoint.Histogram)...)
+	} else {
+		lines = append(lines, statusLines(endpoint.Metrics)...)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i >= height {
+			break
+		}
+		if i > 0 {
+			buffer.WriteString("\r\n")
+		}
+		buffer.WriteString(line)
+	}
+	os.Stdout.Write(buffer.Bytes())
+}
+
+// Render a histogram as a sparkline followed by the count of each bucket
+func histogramLines(histogram *vegeta.Histogram) []string {
+	if histogram == nil || histogram.Total == 0 {
+		return []string{"No requests were completed"}
+	}
+	lines := []string{"Latency histogram", "", sparkline(histogram.Counts), ""}
+	for i, count := range histogram.Counts {
+		bucket := "[" + histogram.Buckets[i].String() + ", "
+		if i+1 < len(histogram.Buckets) {
+			bucket += histogram.Buckets[i+1].String() + ")"
+		} else {
+			bucket += "+Inf]"
+		}
+		lines = append(lines, padRight(bucket, 20)+padLeft(strconv.FormatUint(count, 10), 10)+
+			"  "+formatPercent(float64(count)/float64(histogram.Total)))
+	}
+	return lines
+}
+
+// Render the status codes and errors of an endpoint with their share of the requests
+func statusLines(metrics vegeta.Metrics) []string {
+	if metrics.Requests == 0 {
+		return []string{"No requests were completed"}
+	}
+	codes := make([]string, 0, len(metrics.StatusCodes))
+	for code := range metrics.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	lines := []string{"Status codes", ""}
+	for _, code := range codes {
+		share := float64(metrics.StatusCodes[code]) / float64(metrics.Requests)
+		lines = append(lines, padRight(code, 6)+padLeft(strconv.Itoa(metrics.StatusCodes[code]), 10)+
+			"  "+padRight(formatPercent(share), 8)+strings.Repeat("█", int(share*40+0.5)))
+	}
+	if len(metrics.Errors) > 0 {
+		lines = append(lines, "", "Errors", "")
+		lines = append(lines, metrics.Errors...)
+	}
+	return lines
+}
+
+func sparkline(counts []uint64) string {
+	var max uint64
+	for _, count := range counts {
+		if count > max {
+			max = count
+		}
+	}
+	var spark strings.Builder
+	for _, count := range counts {
+		if max == 0 {
+			spark.WriteRune(sparkTicks[0])
+			continue
+		}
+		spark.WriteRune(sparkTicks[int(count*uint64(len(sparkTicks)-1)/max)])
+	}
+	return spark.String()
+}
+
+func fitTUILine(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return line + strings.Repeat(" ", width-len(runes))
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s + " "
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+func padLeft(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return strings.Repeat(" ", width-len(s)) + s
+}
@@ -0,0 +1,8 @@
+package main
+
+// Whether no request to an endpoint got a response, every one of them timing
+// out or failing to connect
+func isUnreachable(endpoint endpointDetails) bool {
+	metrics := endpoint.Metrics
+	return metrics.Requests > 0 && uint64(metrics.StatusCodes["0"]) == metrics.Requests
+}
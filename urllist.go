@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Read a plain text file of URLs, one per line, as endpoints all queried with
+// the same method and query parameters. Blank lines and lines starting with
+// # are ignored
+func parseURLList(file string, method string, query endpointQuery) []endpointDetails {
+	listFile, err := os.Open(file)
+	if err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	defer listFile.Close()
+
+	var endpoints []endpointDetails
+	scanner := bufio.NewScanner(listFile)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		endpoints = append(endpoints, endpointDetails{
+			Target: endpointTarget{Method: method, URL: line},
+			Query:  query,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		fatal(exitReasonInvalidInput, err)
+	}
+	if len(endpoints) == 0 {
+		fatal(exitReasonInvalidInput, "No URLs found in "+file)
+	}
+	return endpoints
+}
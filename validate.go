@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// Catch the mistakes which would otherwise only fail the run once the
+// endpoint is reached
+func checkEndpoint(endpoint endpointDetails) error {
+	if endpoint.Target.URL == "" {
+		return errors.New("missing target URL")
+	}
+	if _, err := time.ParseDuration(endpoint.Query.Duration); err != nil {
+		return err
+	}
+	if _, err := maxLatency(endpoint); err != nil {
+		return errors.New("invalid max_latency: " + err.Error())
+	}
+	if err := checkMaxRedirects(endpoint.Query); err != nil {
+		return err
+	}
+	if err := checkPacer(endpoint.Query); err != nil {
+		return err
+	}
+	if err := checkBodySize(endpoint.Target); err != nil {
+		return err
+	}
+	if endpoint.Target.BodySize == "" && endpoint.Target.BodyTemplate != "" {
+		if _, err := loadBodyTemplate(endpoint.Target.BodyTemplate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
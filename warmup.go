@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	vegeta "github.com/tsenart/vegeta/v12/lib"
+)
+
+// Latency is averaged over windows of this length while detecting the warm-up
+const warmupWindow = time.Second
+
+// Latency is considered stable once the mean latency of this many consecutive
+// windows is within the tolerance of their moving average
+const (
+	warmupWindows   = 3
+	warmupTolerance = 0.1
+)
+
+type warmupInfo struct {
+	// Whether the latency stabilized, otherwise all requests were measured
+	Stable   bool          `json:"stable" yaml:"stable"`
+	Duration time.Duration `json:"duration" yaml:"duration"`
+	Requests uint64        `json:"requests" yaml:"requests"`
+}
+
+// Detect the end of the warm-up of an attack from the mean latency of its
+// responses over consecutive windows
+type warmupDetector struct {
+	start     time.Time
+	windowEnd time.Time
+	sum       time.Duration
+	count     int
+	means     []float64
+	steadyAt  time.Time
+}
+
+// Add a response and report whether it was received in the steady state
+func (d *warmupDetector) Add(response *vegeta.Result) bool {
+	if !d.steadyAt.IsZero() {
+		return true
+	}
+	if d.start.IsZero() {
+		d.start = response.Timestamp
+		d.windowEnd = d.start.Add(warmupWindow)
+	}
+	for !response.Timestamp.Before(d.windowEnd) {
+		d.closeWindow()
+		if d.stable() {
+			d.steadyAt = d.windowEnd
+			return true
+		}
+		d.windowEnd = d.windowEnd.Add(warmupWindow)
+	}
+	d.sum += response.Latency
+	d.count++
+	return false
+}
+
+func (d *warmupDetector) closeWindow() {
+	if d.count == 0 {
+		return
+	}
+	d.means = append(d.means, float64(d.sum)/float64(d.count))
+	d.sum = 0
+	d.count = 0
+}
+
+// Check whether the latest windows are all close to their moving average
+func (d *warmupDetector) stable() bool {
+	if len(d.means) < warmupWindows {
+		return false
+	}
+	latest := d.means[len(d.means)-warmupWindows:]
+	var average float64
+	for _, mean := range latest {
+		average += mean / warmupWindows
+	}
+	for _, mean := range latest {
+		if math.Abs(mean-average) > average*warmupTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// Describe the warm-up, given the total number of requests of the attack
+func (d *warmupDetector) Result(requests uint64, steadyRequests uint64) *warmupInfo {
+	if d.steadyAt.IsZero() {
+		return &warmupInfo{}
+	}
+	return &warmupInfo{
+		Stable:   true,
+		Duration: d.steadyAt.Sub(d.start),
+		Requests: requests - steadyRequests,
+	}
+}
+
+func printWarmup(warmup *warmupInfo) {
+	if !warmup.Stable {
+		os.Stdout.Write([]byte("WARNING: latency never stabilized, so the warm-up couldn't be excluded\n"))
+		return
+	}
+	os.Stdout.Write([]byte("Warm-up: " + warmup.Duration.String() + " (" + strconv.FormatUint(warmup.Requests, 10) +
+		" requests) excluded, the metrics above cover the steady state only\n"))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Share of the requested rate a probe must achieve for its workers to suffice
+const workerTuningRatio = 0.95
+
+type workerTuning struct {
+	// Maximum number of workers chosen for the test
+	Workers uint64 `json:"workers" yaml:"workers"`
+	// Rates achieved by the last probe and requested, in requests per second
+	Achieved  float64 `json:"achieved" yaml:"achieved"`
+	Requested float64 `json:"requested" yaml:"requested"`
+	Probes    int     `json:"probes" yaml:"probes"`
+	// Whether the ceiling was reached before the requested rate was
+	CeilingHit bool `json:"ceiling_hit" yaml:"ceiling_hit"`
+}
+
+// Find how many workers an endpoint needs to reach its requested rate with
+// short probes, doubling its maximum number of workers from max_threads
+// until a probe achieves the rate or the ceiling is reached, then use that
+// maximum for the test
+func tuneWorkers(endpoint *endpointDetails, ceiling uint64, probeDuration string) {
+	requested := expectedRate(endpoint.Query)
+	if endpoint.Query.WorkerRamp || requested <= 0 {
+		return
+	}
+	workers := endpoint.Query.MaxThreads
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > ceiling {
+		workers = ceiling
+	}
+	tuning := &workerTuning{Requested: requested}
+	for {
+		probe := *endpoint
+		probe.Query.Duration = probeDuration
+		probe.Query.MaxThreads = workers
+		if probe.Query.Threads > workers {
+			probe.Query.Threads = workers
+		}
+		probe.ResponseHeaders = nil
+		queryAPI(&probe, queryOptions{})
+		tuning.Probes++
+		tuning.Workers = workers
+		tuning.Achieved = probe.Metrics.Rate
+		if probe.Metrics.Rate >= requested*workerTuningRatio {
+			break
+		}
+		if workers >= ceiling {
+			tuning.CeilingHit = true
+			log.Print("Even ", ceiling, " workers only queried ", endpointName(*endpoint), " at ", formatRate(probe.Metrics.Rate),
+				" of the requested ", formatRate(requested))
+			break
+		}
+		workers *= 2
+		if workers > ceiling {
+			workers = ceiling
+		}
+	}
+	endpoint.Query.MaxThreads = tuning.Workers
+	if endpoint.Query.Threads > tuning.Workers {
+		endpoint.Query.Threads = tuning.Workers
+	}
+	endpoint.WorkerTuning = tuning
+}
+
+func printWorkerTuning(tuning *workerTuning) {
+	line := "Workers: " + strconv.FormatUint(tuning.Workers, 10) + " chosen after " + strconv.Itoa(tuning.Probes) +
+		" probes, achieving " + formatRate(tuning.Achieved) + " of the requested " + formatRate(tuning.Requested)
+	if tuning.CeilingHit {
+		line += " (ceiling reached)"
+	}
+	os.Stdout.Write([]byte(line + "\n"))
+}